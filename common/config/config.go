@@ -15,17 +15,137 @@ type Database struct {
 	Server   string `json:"server"`
 	Port     uint16 `json:"port"`
 	Database string `json:"database"`
+	SSLMode  string `json:"ssl_mode"` // 仅 postgres 使用，默认为 disable
+}
+
+type Sweeper struct {
+	Interval  uint64 `json:"interval"`   // 两次清理之间间隔的秒数
+	BatchSize int    `json:"batch_size"` // 每批删除的最大行数
+}
+
+type Trash struct {
+	RetentionDays uint64 `json:"retention_days"` // 删除的永久 paste 在回收站中保留的天数，之后彻底删除，为 0 时永久保留
+}
+
+type Bundle struct {
+	MaxFiles     int `json:"max_files"`      // 多文件 paste 的最大文件数
+	MaxFileSize  int `json:"max_file_size"`  // 单个文件的最大字节数
+	MaxTotalSize int `json:"max_total_size"` // 全部文件的最大字节数
+}
+
+type Upload struct {
+	MaxSize int `json:"max_size"` // multipart/form-data 上传的文件的最大字节数
+}
+
+type Analytics struct {
+	FlushInterval uint64 `json:"flush_interval"` // 两次写入查看记录之间间隔的秒数
+	BatchSize     int    `json:"batch_size"`     // 缓冲的 paste 数量达到该值时提前写入
+}
+
+type Preview struct {
+	UserAgents []string `json:"user_agents"` // 链接预览机器人的 User-Agent 中包含的字符串，不区分大小写
+}
+
+type Cache struct {
+	MaxSize int64 `json:"max_size"` // 永久 paste 读缓存的最大字节数，为 0 时不缓存，多个实例之间不共享
+}
+
+type S3 struct {
+	Endpoint  string `json:"endpoint"` // 例如 http://minio:9000
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+type Storage struct {
+	Type      string `json:"type"`      // database、filesystem 或 s3
+	Threshold int    `json:"threshold"` // 超过该字节数的内容才会放入外部存储
+	Root      string `json:"root"`      // filesystem 的根目录，默认为数据目录下的 content
+	S3        S3     `json:"s3"`
+}
+
+type Compression struct {
+	Encoding string `json:"encoding"` // 目前只支持 gzip，为空时不压缩
+	MinSize  int    `json:"min_size"` // 不小于该字节数的内容才会压缩
+}
+
+type Encryption struct {
+	MasterKey string `json:"master_key"` // 不为空时，没有设置密码的内容也会加密存储
+}
+
+type Key struct {
+	Length   int    `json:"length"`   // key 的长度，不超过 16
+	Alphabet string `json:"alphabet"` // key 使用的字符，必须包含 0，同时包含大小写字母时区分大小写
+	Source   string `json:"source"`   // 随机数来源，crypto 或 math
+
+	VanityTrustLevel int `json:"vanity_trust_level"` // 允许使用自定义 key 的最低信任等级
 }
 
 type config struct {
-	Address  string   `json:"address"`
-	Port     uint16   `json:"port"`
-	Secret   string   `json:"secret"`
-	LogFile  string   `json:"log_file"`
-	Database Database `json:"database"`
+	Address     string      `json:"address"`
+	Port        uint16      `json:"port"`
+	Secret      string      `json:"secret"`
+	LogFile     string      `json:"log_file"`
+	AutoMigrate bool        `json:"auto_migrate"` // 启动时自动执行未执行的 migration
+	Database    Database    `json:"database"`
+	Sweeper     Sweeper     `json:"sweeper"`
+	Storage     Storage     `json:"storage"`
+	Compression Compression `json:"compression"`
+	Encryption  Encryption  `json:"encryption"`
+	Key         Key         `json:"key"`
+	Trash       Trash       `json:"trash"`
+	Bundle      Bundle      `json:"bundle"`
+	Upload      Upload      `json:"upload"`
+	Cache       Cache       `json:"cache"`
+	Analytics   Analytics   `json:"analytics"`
+	Preview     Preview     `json:"preview"`
+	Admins      []string    `json:"admins"` // 管理员的用户名，可以删除和恢复任意 paste
 }
 
-var Config config
+var Config = config{
+	AutoMigrate: true,
+	Sweeper: Sweeper{
+		Interval:  60,
+		BatchSize: 500,
+	},
+	Storage: Storage{
+		Type:      "database",
+		Threshold: 64 * 1024,
+	},
+	Compression: Compression{
+		Encoding: "gzip",
+		MinSize:  1024,
+	},
+	Key: Key{
+		Length:   8,
+		Alphabet: "qwertyuiopasdfghjklzxcvbnm0123456789",
+		Source:   "crypto",
+
+		VanityTrustLevel: 3,
+	},
+	Trash: Trash{
+		RetentionDays: 30,
+	},
+	Bundle: Bundle{
+		MaxFiles:     20,
+		MaxFileSize:  1024 * 1024,
+		MaxTotalSize: 8 * 1024 * 1024,
+	},
+	Upload: Upload{
+		MaxSize: 10 * 1024 * 1024,
+	},
+	Cache: Cache{
+		MaxSize: 32 * 1024 * 1024,
+	},
+	Analytics: Analytics{
+		FlushInterval: 10,
+		BatchSize:     1000,
+	},
+	Preview: Preview{
+		UserAgents: []string{"slackbot", "telegrambot", "discordbot", "facebookexternalhit", "twitterbot"},
+	},
+}
 
 func init() {
 	load(flag.Config)
@@ -12,6 +12,7 @@ var (
 	Config  string
 	Debug   bool
 	DataDir string
+	Command []string // 选项之后的子命令，例如 migrate up
 )
 
 func init() {
@@ -23,6 +24,7 @@ func init() {
 func init() {
 	testing.Init()
 	flag.Parse()
+	Command = flag.Args()
 	validationCheck(DataDir)
 }
 
@@ -0,0 +1,112 @@
+package password
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"regexp"
+	"strings"
+)
+
+// argon2id 参数，取自 OWASP 推荐的最低配置
+const (
+	argonMemory  = 19 * 1024
+	argonTime    = 2
+	argonThreads = 1
+	keyLength    = 32
+	SaltLength   = 16
+)
+
+var (
+	legacyPattern    = regexp.MustCompile("^[0-9a-f]{32}$")
+	errInvalidFormat = errors.New("invalid password hash format")
+	encoding         = base64.RawStdEncoding
+)
+
+// Hash 使用 argon2id 和随机盐生成 PHC 格式的哈希，空密码表示没有密码
+func Hash(text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	salt, err := Salt()
+	if err != nil {
+		return "", err
+	}
+	key := DeriveKey(text, salt)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads, encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// Salt 生成 SaltLength 字节的随机盐
+func Salt() ([]byte, error) {
+	salt := make([]byte, SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// DeriveKey 使用 argon2id 由密码派生 32 字节的密钥，可用于 AES-256
+func DeriveKey(text string, salt []byte) []byte {
+	return argon2.IDKey([]byte(text), salt, argonTime, argonMemory, argonThreads, keyLength)
+}
+
+// Verify 校验密码，needRehash 表示哈希是旧格式或旧参数，校验通过后应当用 Hash 重新生成
+func Verify(hashed string, text string) (ok bool, needRehash bool) {
+	if hashed == "" || text == "" {
+		return hashed == text, false
+	}
+	if IsLegacy(hashed) {
+		sum := fmt.Sprintf("%x", md5.Sum([]byte(text)))
+		return subtle.ConstantTimeCompare([]byte(hashed), []byte(sum)) == 1, true
+	}
+
+	params, salt, key, err := decode(hashed)
+	if err != nil {
+		return false, false
+	}
+	actual := argon2.IDKey([]byte(text), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, actual) != 1 {
+		return false, false
+	}
+	return true, params != defaultParams
+}
+
+// IsLegacy 判断是否为旧版本使用的无盐 MD5 哈希
+func IsLegacy(hashed string) bool {
+	return legacyPattern.MatchString(hashed)
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+var defaultParams = argon2Params{memory: argonMemory, time: argonTime, threads: argonThreads}
+
+func decode(hashed string) (params argon2Params, salt []byte, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errInvalidFormat
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errInvalidFormat
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, errInvalidFormat
+	}
+	if salt, err = encoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, errInvalidFormat
+	}
+	if key, err = encoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidFormat
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,53 @@
+package password
+
+import (
+	"crypto/md5"
+	"fmt"
+	"testing"
+)
+
+func assertEqual(t *testing.T, expect interface{}, got interface{}) {
+	if expect != got {
+		t.Fatalf("expect %+v, got %+v", expect, got)
+	}
+}
+
+func TestHash(t *testing.T) {
+	hashed, err := Hash("secret")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	another, _ := Hash("secret")
+	assertEqual(t, true, hashed != another) // 每次使用不同的盐
+
+	ok, needRehash := Verify(hashed, "secret")
+	assertEqual(t, true, ok)
+	assertEqual(t, false, needRehash)
+
+	ok, _ = Verify(hashed, "wrong")
+	assertEqual(t, false, ok)
+	ok, _ = Verify(hashed, "")
+	assertEqual(t, false, ok)
+}
+
+func TestEmpty(t *testing.T) {
+	hashed, _ := Hash("")
+	assertEqual(t, "", hashed)
+
+	ok, _ := Verify("", "")
+	assertEqual(t, true, ok)
+	ok, _ = Verify("", "secret")
+	assertEqual(t, false, ok)
+}
+
+func TestLegacy(t *testing.T) {
+	legacy := fmt.Sprintf("%x", md5.Sum([]byte("secret")))
+	assertEqual(t, true, IsLegacy(legacy))
+
+	ok, needRehash := Verify(legacy, "secret")
+	assertEqual(t, true, ok)
+	assertEqual(t, true, needRehash)
+
+	ok, _ = Verify(legacy, "wrong")
+	assertEqual(t, false, ok)
+}
@@ -3,6 +3,9 @@
 BASE=github.com/PasteUs/PasteMeGoBackend/
 
 PACKAGE_LISTS="
+common/password
+model/migration
+model/store
 model/paste
 handler/paste
 router
@@ -10,6 +13,9 @@ router
 
 export UNITTEST=1
 
+# 全文搜索在 SQLite 上需要 FTS5
+TAGS=sqlite_fts5
+
 clear() {
     rm -f "${1}/pasteme.db"
     rm -f "${1}/pasteme.log"
@@ -21,20 +27,20 @@ if [[ ${#} == 1 ]]; then
         exit ${?}
     fi
     clear "${1}"
-    go test -count=1 -cover "${BASE}${1}"
+    go test -count=1 -cover -tags "${TAGS}" "${BASE}${1}"
     exit ${?}
 fi
 
 for PACKAGE in ${PACKAGE_LISTS}; do
     clear "${PACKAGE}"
 
-    if [[ ${PACKAGE} == "util" ]]; then
-        if ! go test -count=1 -cover "${BASE}${PACKAGE}"; then
+    if [[ ${PACKAGE} == "common/password" ]]; then
+        if ! go test -count=1 -cover -tags "${TAGS}" "${BASE}${PACKAGE}"; then
             echo "test ${PACKAGE} failed"
             exit 1
         fi
     else
-        if ! go test -count=1 -cover "${BASE}${PACKAGE}" -args -c "${PWD}/config.json" --debug; then
+        if ! go test -count=1 -cover -tags "${TAGS}" "${BASE}${PACKAGE}" -args -c "${PWD}/config.json" --debug; then
             echo "test ${PACKAGE} failed"
             exit 1
         fi
@@ -16,18 +16,59 @@ var (
 	ErrWrongParamType                 = New(http.StatusBadRequest, 8, "wrong param type")
 	ErrInvalidKeyLength               = New(http.StatusBadRequest, 9, "invalid key length")
 	ErrInvalidKeyFormat               = New(http.StatusBadRequest, 10, "invalid key format")
+	ErrInvalidEncryptedContent        = New(http.StatusBadRequest, 11, "invalid encrypted content")
+	ErrEmptyIVOrSalt                  = New(http.StatusBadRequest, 12, "empty iv or salt")
+	ErrInvalidCustomKey               = New(http.StatusBadRequest, 13, "invalid custom key")
+	ErrReservedKey                    = New(http.StatusBadRequest, 14, "reserved key")
+	ErrCustomKeyForTemporary          = New(http.StatusBadRequest, 15, "custom key is not allowed for self destruct paste")
+	ErrNotEditable                    = New(http.StatusBadRequest, 16, "self destruct paste is not editable")
+	ErrInvalidRevision                = New(http.StatusBadRequest, 17, "invalid revision")
+	ErrNotPermanent                   = New(http.StatusBadRequest, 18, "only permanent paste supported")
+	ErrTooManyFiles                   = New(http.StatusBadRequest, 19, "too many files")
+	ErrInvalidFileName                = New(http.StatusBadRequest, 20, "invalid file name")
+	ErrDuplicateFileName              = New(http.StatusBadRequest, 21, "duplicate file name")
+	ErrFileTooLarge                   = New(http.StatusBadRequest, 22, "file too large")
+	ErrBundleTooLarge                 = New(http.StatusBadRequest, 23, "bundle too large")
+	ErrEncryptedBundle                = New(http.StatusBadRequest, 24, "encrypted paste can not have files")
+	ErrInvalidArchiveFormat           = New(http.StatusBadRequest, 25, "invalid archive format")
+	ErrEmptyFile                      = New(http.StatusBadRequest, 26, "empty file")
+	ErrInvalidQuery                   = New(http.StatusBadRequest, 27, "invalid query")
+	ErrInvalidTag                     = New(http.StatusBadRequest, 28, "invalid tag")
+	ErrTooManyTags                    = New(http.StatusBadRequest, 29, "too many tags")
+	ErrInvalidCollectionName          = New(http.StatusBadRequest, 30, "invalid collection name")
+	ErrTooManyCollectionItems         = New(http.StatusBadRequest, 31, "too many collection items")
+	ErrInvalidCollectionItem          = New(http.StatusBadRequest, 32, "collection item must be an existing permanent paste")
+	ErrConflictingExpiry              = New(http.StatusBadRequest, 33, "expire_second and expire_at are mutually exclusive")
+	ErrExpireTimeInPast               = New(http.StatusBadRequest, 34, "expire time in the past")
+	ErrDeleteAtForTemporary           = New(http.StatusBadRequest, 35, "delete_at is only for permanent paste")
+	ErrNotExtended                    = New(http.StatusBadRequest, 36, "expiry can only be extended")
 
 	ErrUnauthorized = New(http.StatusUnauthorized, 1, "unauthorized")
 
 	ErrInsufficient_level = New(http.StatusForbidden, 1, "insufficient level")
 
-	ErrWrongPassword = New(http.StatusForbidden, 1, "wrong password")
+	ErrWrongPassword    = New(http.StatusForbidden, 1, "wrong password")
+	ErrNotOwner         = New(http.StatusForbidden, 2, "not the owner")
+	ErrWrongDeleteToken = New(http.StatusForbidden, 3, "wrong delete token")
+	ErrNotAdmin         = New(http.StatusForbidden, 4, "admin only")
+	ErrPreviewAgent     = New(http.StatusForbidden, 5, "link preview can not reveal paste")
 
 	ErrNoRouterFounded = New(http.StatusNotFound, 1, "no router founded")
 	ErrRecordNotFound  = New(http.StatusNotFound, 2, "record not found")
+	ErrFileNotFound    = New(http.StatusNotFound, 3, "file not found")
+
+	ErrKeyConflict = New(http.StatusConflict, 1, "key already exists")
+
+	ErrRevisionMismatch = New(http.StatusPreconditionFailed, 1, "revision mismatch")
+
+	ErrRevisionRequired = New(http.StatusPreconditionRequired, 1, "if-match header required")
+
+	ErrUploadTooLarge = New(http.StatusRequestEntityTooLarge, 1, "upload too large")
 
 	ErrQueryDBFailed = New(http.StatusInternalServerError, 1, "query from db failed")
 	ErrSaveFailed    = New(http.StatusInternalServerError, 2, "save failed")
+	ErrKeyCollision  = New(http.StatusInternalServerError, 3, "key collision")
+	ErrArchiveFailed = New(http.StatusInternalServerError, 4, "write archive failed")
 )
 
 type ErrorResponse struct {
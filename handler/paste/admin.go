@@ -0,0 +1,35 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// CacheStats godoc
+// @Summary 读缓存统计
+// @Description 返回永久的一贴的读缓存的命中、未命中、淘汰次数以及占用的字节数，只有管理员可以查看
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} CacheStatsResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /admin/cache [get]
+func CacheStats(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+	if !isAdmin(user) {
+		common.ErrNotAdmin.Abort(context)
+		return
+	}
+
+	common.JSON(context, CacheStatsResponse{
+		Response:   &common.Response{Code: http.StatusOK},
+		CacheStats: model.ReadCacheStats(),
+	})
+}
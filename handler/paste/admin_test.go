@@ -0,0 +1,22 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"net/http"
+	"testing"
+)
+
+func TestCacheStats(t *testing.T) {
+	admins := config.Config.Admins
+	config.Config.Admins = []string{"root"}
+	defer func() {
+		config.Config.Admins = admins
+	}()
+
+	if recorder := serve(CacheStats, http.MethodGet, "/", nil, "mallory", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("non admin: expect 403, got %d", recorder.Code)
+	}
+	if recorder := serve(CacheStats, http.MethodGet, "/", nil, "root", nil); recorder.Code != http.StatusOK {
+		t.Errorf("admin: expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,71 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// recordView 记录永久的一贴的一次查看，自我销毁的一贴有自己的查看次数，链接预览不是真正的查看，都不统计
+// IP 只用于估算独立查看者的数量，不会保存
+func recordView(context *gin.Context, paste model.IPaste) {
+	if _, ok := paste.(*model.Temporary); ok || isPreview(context) {
+		return
+	}
+	model.RecordView(paste.GetKey(), context.ClientIP())
+}
+
+// Stats godoc
+// @Summary 查看统计
+// @Description 返回永久的一贴的查看次数、估算的独立查看者数量以及按时间汇总的查看次数，只有创建者和管理员可以查看
+// @Description 查看记录分批写入数据库，最近的查看可能尚未计入
+// @Tags Paste
+// @Produce json
+// @Param key path string true "索引"
+// @Param interval query string false "hour 为最近 48 小时，day 为最近 30 天" default(hour)
+// @Success 200 {object} StatsResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/stats [get]
+func Stats(context *gin.Context) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
+	}
+	temporary, errorResponse := temporaryKey(key)
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return
+	}
+	if temporary {
+		common.ErrNotPermanent.Abort(context)
+		return
+	}
+
+	interval := context.DefaultQuery("interval", "hour")
+	if interval != "hour" && interval != "day" {
+		common.ErrInvalidQuery.Abort(context)
+		return
+	}
+
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	stats, err := (&model.Permanent{AbstractPaste: &model.AbstractPaste{Key: key}}).Stats(user.Username, isAdmin(user), interval == "day")
+	if err != nil {
+		abortWithError(context, "stats", err)
+		return
+	}
+
+	common.JSON(context, StatsResponse{
+		Response:  &common.Response{Code: http.StatusOK},
+		Key:       key,
+		ViewStats: stats,
+	})
+}
@@ -0,0 +1,43 @@
+package paste
+
+import (
+	"encoding/json"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	paste := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "shared", Username: "frank"}}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "key", Value: paste.Key}}
+
+	model.StartAnalytics(time.Hour, 0)
+	for i := 0; i < 3; i++ {
+		if recorder := serve(Get, http.MethodGet, "/", params, "", nil); recorder.Code != http.StatusOK {
+			t.Fatalf("get: expect 200, got %d", recorder.Code)
+		}
+	}
+	model.StopAnalytics() // 停止时写入剩余的查看记录
+
+	if recorder := serve(Stats, http.MethodGet, "/", params, "mallory", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("stats by others: expect 403, got %d", recorder.Code)
+	}
+	recorder := serve(Stats, http.MethodGet, "/", params, "frank", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var response StatsResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.ViewStats == nil || response.Views != 3 || response.UniqueViewers != 1 {
+		t.Errorf("unexpected stats %s", recorder.Body.String())
+	}
+	if strings.Contains(recorder.Body.String(), "192.0.2.1") {
+		t.Errorf("stats must not expose ip: %s", recorder.Body.String())
+	}
+}
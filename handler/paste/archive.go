@@ -0,0 +1,52 @@
+package paste
+
+import (
+	"archive/tar"
+	"archive/zip"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"io"
+	"time"
+)
+
+var (
+	archivers = map[string]func(io.Writer, []model.File) error{
+		"zip": writeZip,
+		"tar": writeTar,
+	}
+	archiveContentType = map[string]string{
+		"zip": "application/zip",
+		"tar": "application/x-tar",
+	}
+)
+
+func writeZip(writer io.Writer, files []model.File) error {
+	archive := zip.NewWriter(writer)
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, file.Content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeTar(writer io.Writer, files []model.File) error {
+	archive := tar.NewWriter(writer)
+	for _, file := range files {
+		if err := archive.WriteHeader(&tar.Header{
+			Name:    file.Name,
+			Mode:    0644,
+			Size:    int64(len(file.Content)),
+			ModTime: time.Now(),
+		}); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(archive, file.Content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,83 @@
+package paste
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"testing"
+)
+
+var archiveFiles = []model.File{
+	{Name: "Dockerfile", Lang: "bash", Content: "FROM golang:1.22"},
+	{Name: "config.json", Lang: "json", Content: `{"debug": true}`},
+}
+
+func TestWriteZip(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := writeZip(&buffer, archiveFiles); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reader.File) != len(archiveFiles) {
+		t.Fatalf("expect %d files, got %d", len(archiveFiles), len(reader.File))
+	}
+	for i, file := range reader.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		if file.Name != archiveFiles[i].Name || string(content) != archiveFiles[i].Content {
+			t.Errorf("unexpected file %s: %s", file.Name, content)
+		}
+	}
+}
+
+func TestWriteTar(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := writeTar(&buffer, archiveFiles); err != nil {
+		t.Fatal(err)
+	}
+	reader := tar.NewReader(&buffer)
+	for _, expect := range archiveFiles {
+		header, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(reader)
+		if header.Name != expect.Name || string(content) != expect.Content {
+			t.Errorf("unexpected file %s: %s", header.Name, content)
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expect EOF, got %v", err)
+	}
+}
+
+func TestDownloadArchiveFailed(t *testing.T) {
+	paste := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "archive me"}}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+	archivers["broken"] = func(io.Writer, []model.File) error {
+		return errors.New("disk full")
+	}
+	defer delete(archivers, "broken")
+
+	recorder := serve(Download, http.MethodGet, "/?format=broken", gin.Params{{Key: "key", Value: paste.Key}}, "", nil)
+	var response common.ErrorResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if recorder.Code != http.StatusInternalServerError || response.Response == nil || response.Code != common.ErrArchiveFailed.Code {
+		t.Fatalf("expect archive failed, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,165 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// bindCollection 解析创建与修改合集的请求体
+func bindCollection(context *gin.Context) (*model.Collection, bool) {
+	var requestBody CollectionRequest
+	if err := context.ShouldBindJSON(&requestBody); err != nil {
+		logging.Warn("bind body failed", zap.Error(err))
+		common.ErrWrongParamType.Abort(context)
+		return nil, false
+	}
+	return &model.Collection{Name: requestBody.Name, Keys: requestBody.Keys}, true
+}
+
+// renderCollection 读取合集并以一个 JSON 文档返回
+func renderCollection(context *gin.Context, collection *model.Collection, code int) {
+	if err := collection.Get(); err != nil {
+		abortWithError(context, "get collection", err)
+		return
+	}
+	common.JSON(context, CollectionResponse{
+		Response:   &common.Response{Code: code},
+		Collection: collection,
+	})
+}
+
+// CreateCollection godoc
+// @Summary 创建合集
+// @Description 合集是按顺序排列的一组永久的一贴，任何人都可以通过 /collection/{id} 读取，成员需要是已经存在的永久的一贴
+// @Tags Collection
+// @Accept json
+// @Produce json
+// @Param request body CollectionRequest true "名称与成员"
+// @Success 201 {object} CollectionResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/collections [post]
+func CreateCollection(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	collection, ok := bindCollection(context)
+	if !ok {
+		return
+	}
+	collection.Username = user.Username
+	if err := collection.Save(); err != nil {
+		abortWithError(context, "create collection", err)
+		return
+	}
+
+	logging.Info("create collection", zap.String("id", collection.ID), zap.String("username", user.Username))
+	renderCollection(context, collection, http.StatusCreated)
+}
+
+// UpdateCollection godoc
+// @Summary 修改合集
+// @Description 替换合集的名称与全部成员，只有创建者可以修改
+// @Tags Collection
+// @Accept json
+// @Produce json
+// @Param id path string true "合集的 ID"
+// @Param request body CollectionRequest true "名称与成员"
+// @Success 200 {object} CollectionResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/collections/{id} [put]
+func UpdateCollection(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	collection, ok := bindCollection(context)
+	if !ok {
+		return
+	}
+	collection.ID = context.Param("id")
+	if err := collection.Update(user.Username); err != nil {
+		abortWithError(context, "update collection", err)
+		return
+	}
+	renderCollection(context, collection, http.StatusOK)
+}
+
+// DeleteCollection godoc
+// @Summary 删除合集
+// @Description 只删除合集本身，其中的一贴不受影响，只有创建者可以删除
+// @Tags Collection
+// @Produce json
+// @Param id path string true "合集的 ID"
+// @Success 200 {object} common.Response
+// @Failure default {object} common.ErrorResponse
+// @Router /user/collections/{id} [delete]
+func DeleteCollection(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	collection := model.Collection{ID: context.Param("id")}
+	if err := collection.Delete(user.Username); err != nil {
+		abortWithError(context, "delete collection", err)
+		return
+	}
+
+	logging.Info("delete collection", zap.String("id", collection.ID), zap.String("username", user.Username))
+	common.JSON(context, &common.Response{Code: http.StatusOK})
+}
+
+// Collections godoc
+// @Summary 列出自己的合集
+// @Description 列出当前用户的全部合集，不包含成员，按修改时间从新到旧排列
+// @Tags Collection
+// @Produce json
+// @Success 200 {object} CollectionsResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/collections [get]
+func Collections(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	collections, err := model.Collections(user.Username)
+	if err != nil {
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+
+	common.JSON(context, CollectionsResponse{
+		Response:    &common.Response{Code: http.StatusOK},
+		Collections: collections,
+	})
+}
+
+// GetCollection godoc
+// @Summary 读取合集
+// @Description 以一个 JSON 文档返回合集以及按顺序排列的全部成员的元信息，不需要登陆，不包含成员的内容
+// @Tags Collection
+// @Produce json
+// @Param id path string true "合集的 ID"
+// @Success 200 {object} CollectionResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /collection/{id} [get]
+func GetCollection(context *gin.Context) {
+	renderCollection(context, &model.Collection{ID: context.Param("id")}, http.StatusOK)
+}
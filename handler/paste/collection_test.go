@@ -0,0 +1,73 @@
+package paste
+
+import (
+	"encoding/json"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+)
+
+func TestCollection(t *testing.T) {
+	paste := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "go", Content: "package main", Username: "erin"}}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder := serve(CreateCollection, http.MethodPost, "/", nil, "erin",
+		CollectionRequest{Name: "broken", Keys: []string{"0temporary"}}); recorder.Code != http.StatusBadRequest {
+		t.Errorf("temporary member: expect 400, got %d", recorder.Code)
+	}
+
+	recorder := serve(CreateCollection, http.MethodPost, "/", nil, "erin", CollectionRequest{Name: "services", Keys: []string{paste.Key}})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expect 201, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var created CollectionResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &created)
+
+	params := gin.Params{{Key: "id", Value: created.ID}}
+	recorder = serve(GetCollection, http.MethodGet, "/", params, "", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var document struct {
+		Name   string            `json:"name"`
+		Pastes []model.PasteInfo `json:"pastes"`
+	}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &document)
+	if document.Name != "services" || len(document.Pastes) != 1 || document.Pastes[0].Key != paste.Key {
+		t.Errorf("unexpected collection %s", recorder.Body.String())
+	}
+
+	if recorder = serve(DeleteCollection, http.MethodDelete, "/", params, "mallory", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("delete by others: expect 403, got %d", recorder.Code)
+	}
+	if recorder = serve(DeleteCollection, http.MethodDelete, "/", params, "erin", nil); recorder.Code != http.StatusOK {
+		t.Errorf("delete: expect 200, got %d", recorder.Code)
+	}
+	if recorder = serve(GetCollection, http.MethodGet, "/", params, "", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("deleted: expect 404, got %d", recorder.Code)
+	}
+}
+
+func TestSetTags(t *testing.T) {
+	paste := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "go", Content: "package main", Username: "erin"}}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "key", Value: paste.Key}}
+
+	if recorder := serve(SetTags, http.MethodPut, "/", params, "mallory", TagsRequest{Tags: []string{"x"}}); recorder.Code != http.StatusForbidden {
+		t.Errorf("set by others: expect 403, got %d", recorder.Code)
+	}
+	recorder := serve(SetTags, http.MethodPut, "/", params, "erin", TagsRequest{Tags: []string{"Go", "service"}})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var response TagsResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if len(response.Tags) != 2 || response.Tags[0] != "go" {
+		t.Errorf("unexpected tags %+v", response.Tags)
+	}
+}
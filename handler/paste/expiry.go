@@ -0,0 +1,167 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// createRequest 转为创建时的请求，以便按相同的信任等级限制鉴权
+// 保留时间从一贴的 createdAt 开始计算，限制的是总的保留时间，否则可以不断延长
+func (body ExpiryRequest) createRequest(temporary bool, createdAt time.Time) CreateRequest {
+	if !temporary || body.Permanent {
+		return CreateRequest{DeleteAt: body.DeleteAt}
+	}
+	request := CreateRequest{SelfDestruct: true, ExpireCount: body.ExpireCount}
+	elapsed := uint64(time.Since(createdAt).Seconds())
+	if body.ExpireSecond > 0 {
+		request.ExpireSecond = elapsed + body.ExpireSecond
+	}
+	if body.ExpireAt != nil {
+		if total := body.ExpireAt.Sub(createdAt); total > 0 {
+			request.ExpireSecond = uint64(total.Seconds())
+		}
+	}
+	if body.IdleSecond > 0 {
+		request.IdleSecond = elapsed + body.IdleSecond // 闲置过期从本次延长重新计时
+	}
+	return request
+}
+
+func (body ExpiryRequest) options() model.ExpiryOptions {
+	options := model.ExpiryOptions{
+		ExpireAt:    body.ExpireAt,
+		IdleSecond:  body.IdleSecond,
+		ExpireCount: body.ExpireCount,
+		Permanent:   body.Permanent,
+		DeleteAt:    body.DeleteAt,
+	}
+	if body.ExpireSecond > 0 {
+		expireAt := time.Now().Add(time.Second * time.Duration(body.ExpireSecond))
+		options.ExpireAt = &expireAt
+	}
+	return options
+}
+
+// expiryValidator 校验延长过期时间的参数，上限与创建时相同，从 createdAt 开始计算
+func expiryValidator(body ExpiryRequest, temporary bool, createdAt time.Time) *common.ErrorResponse {
+	if !temporary || body.Permanent {
+		if body.DeleteAt != nil && !body.DeleteAt.After(time.Now()) {
+			return common.ErrExpireTimeInPast
+		}
+		return nil
+	}
+
+	if body.DeleteAt != nil {
+		return common.ErrDeleteAtForTemporary
+	}
+	if body.ExpireSecond > 0 && body.ExpireAt != nil {
+		return common.ErrConflictingExpiry
+	}
+	if body.ExpireSecond == 0 && body.ExpireAt == nil && body.IdleSecond == 0 && body.ExpireCount == 0 {
+		return common.ErrNotExtended
+	}
+	if body.ExpireAt != nil && !body.ExpireAt.After(time.Now()) {
+		return common.ErrExpireTimeInPast
+	}
+	if lifetime(body.createRequest(temporary, createdAt)) > model.OneMonth {
+		return common.ErrExpireSecondGreaterThanMonth
+	}
+	if body.ExpireCount > model.MaxCount {
+		return common.ErrExpireCountGreaterThanMaxCount
+	}
+	return nil
+}
+
+// Extend godoc
+// @Summary 延长过期时间
+// @Description 创建者可以延长自我销毁的一贴的过期时间与剩余查看次数，或者保留原来的 key 转为永久的一贴
+// @Description 永久的一贴可以推迟或取消设置的删除时间，只能延长不能缩短，上限与创建时的信任等级限制相同，从创建时开始计算
+// @Description 每一次延长都会被记录，响应中包含全部的延长记录
+// @Tags Paste
+// @Accept json
+// @Produce json
+// @Param key path string true "索引"
+// @Param data body ExpiryRequest true "请求数据"
+// @Success 200 {object} ExpiryResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/expiry [patch]
+func Extend(context *gin.Context) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
+	}
+
+	accessToken, err := context.Cookie("access_token")
+	if err != nil || accessToken == "" {
+		logging.Info("missing access token in cookie, unauthorized")
+		common.ErrUnauthorized.Abort(context)
+		return
+	}
+
+	var requestBody ExpiryRequest
+	if err := context.ShouldBindJSON(&requestBody); err != nil {
+		logging.Warn("bind body failed", zap.Error(err))
+		common.ErrWrongParamType.Abort(context)
+		return
+	}
+
+	temporary, errorResponse := temporaryKey(key)
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return
+	}
+	var createdAt time.Time
+	if temporary {
+		meta, err := model.ReadMeta(key)
+		if err != nil {
+			abortWithError(context, "read meta", err)
+			return
+		}
+		createdAt = meta.CreatedAt
+	}
+	if err := expiryValidator(requestBody, temporary, createdAt); err != nil {
+		logging.Info("invalid request", zap.String("message", err.Message))
+		err.Abort(context)
+		return
+	}
+	user, errorResponse := authenticator(requestBody.createRequest(temporary, createdAt), accessToken)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	abstractPaste := model.AbstractPaste{Key: key}
+	if temporary {
+		err = (&model.Temporary{AbstractPaste: &abstractPaste}).Extend(user.Username, requestBody.options())
+	} else {
+		err = (&model.Permanent{AbstractPaste: &abstractPaste}).Extend(user.Username, requestBody.options())
+	}
+	if err != nil {
+		abortWithError(context, "extend expiry", err)
+		return
+	}
+	logging.Info("expiry extended", zap.String("key", key), zap.Bool("permanent", requestBody.Permanent))
+
+	meta, err := model.ReadMeta(key)
+	if err != nil {
+		abortWithError(context, "read meta", err)
+		return
+	}
+	extensions, err := model.Extensions(key)
+	if err != nil {
+		abortWithError(context, "list extensions", err)
+		return
+	}
+	common.JSON(context, ExpiryResponse{
+		Response:   &common.Response{Code: http.StatusOK},
+		Meta:       meta,
+		Extensions: extensions,
+	})
+}
@@ -0,0 +1,113 @@
+package paste
+
+import (
+	"encoding/json"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExtend(t *testing.T) {
+	paste := model.Temporary{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "incident notes", Username: "responder"}, ExpireSecond: 60, ExpireCount: 1}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "key", Value: paste.Key}}
+
+	for name, c := range map[string]struct {
+		username string
+		body     ExpiryRequest
+		expect   *common.ErrorResponse
+	}{
+		"not_owner":   {"mallory", ExpiryRequest{ExpireCount: 5}, common.ErrNotOwner},
+		"empty":       {"responder", ExpiryRequest{}, common.ErrNotExtended},
+		"delete_at":   {"responder", ExpiryRequest{ExpireCount: 5, DeleteAt: paste.ExpireAt}, common.ErrDeleteAtForTemporary},
+		"over_month":  {"responder", ExpiryRequest{ExpireSecond: model.OneMonth + 1}, common.ErrExpireSecondGreaterThanMonth},
+		"over_global": {"responder", ExpiryRequest{ExpireCount: model.MaxCount + 1}, common.ErrExpireCountGreaterThanMaxCount},
+	} {
+		recorder := serve(Extend, http.MethodPatch, "/", params, c.username, c.body)
+		if recorder.Code != c.expect.GetHttpStatusCode() {
+			t.Errorf("%s: expect %d, got %d %s", name, c.expect.GetHttpStatusCode(), recorder.Code, recorder.Body.String())
+		}
+	}
+
+	// 信任等级的上限与创建时相同
+	origin := fetchUser
+	fetchUser = func(accessToken string) (*OAuthUser, error) {
+		return &OAuthUser{ID: 1, Username: accessToken, TrustLevel: 1, Active: true}, nil
+	}
+	if recorder := serve(Extend, http.MethodPatch, "/", params, "responder", ExpiryRequest{ExpireSecond: 24 * 60 * 60}); recorder.Code != http.StatusForbidden {
+		t.Errorf("level 1 over ceiling: expect 403, got %d", recorder.Code)
+	}
+	if recorder := serve(Extend, http.MethodPatch, "/", params, "responder", ExpiryRequest{Permanent: true}); recorder.Code != http.StatusForbidden {
+		t.Errorf("level 1 convert: expect 403, got %d", recorder.Code)
+	}
+	recorder := serve(Extend, http.MethodPatch, "/", params, "responder", ExpiryRequest{ExpireSecond: 60 * 60, ExpireCount: 3})
+	fetchUser = origin
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("extend: expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var response ExpiryResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Meta == nil || *response.RemainingViews != 3 || len(response.Extensions) != 1 {
+		t.Fatalf("unexpected response %s", recorder.Body.String())
+	}
+
+	recorder = serve(Extend, http.MethodPatch, "/", params, "responder", ExpiryRequest{Permanent: true})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("convert: expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	response = ExpiryResponse{}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Type != model.TypePermanent || response.RemainingViews != nil || len(response.Extensions) != 2 || !response.Extensions[1].Permanent {
+		t.Fatalf("unexpected response %s", recorder.Body.String())
+	}
+
+	// 原来的 key 仍然可以读取，并且不再消耗查看次数
+	for i := 0; i < 5; i++ {
+		recorder = serveAs(Get, http.MethodGet, paste.Key, "Mozilla/5.0")
+		if recorder.Code != http.StatusOK || recorder.Body.String() != "incident notes" {
+			t.Fatalf("read converted paste: got %d %s", recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestExtendTotalLifetime(t *testing.T) {
+	paste := model.Temporary{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "shift handover", Username: "night-shift"}, ExpireSecond: 60, ExpireCount: 1}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "key", Value: paste.Key}}
+
+	origin := fetchUser
+	defer func() {
+		fetchUser = origin
+	}()
+	fetchUser = func(accessToken string) (*OAuthUser, error) {
+		return &OAuthUser{ID: 1, Username: accessToken, TrustLevel: 1, Active: true}, nil
+	}
+
+	// 每次延长 5 小时并在 5 小时后再次延长，信任等级 1 的总保留时间不能超过 12 小时
+	createdAt := paste.CreatedAt
+	for i, expect := range []int{http.StatusOK, http.StatusOK, http.StatusForbidden} {
+		recorder := serve(Extend, http.MethodPatch, "/", params, "night-shift", ExpiryRequest{ExpireSecond: 5 * 60 * 60})
+		if recorder.Code != expect {
+			t.Fatalf("extension %d: expect %d, got %d %s", i+1, expect, recorder.Code, recorder.Body.String())
+		}
+		createdAt = createdAt.Add(-5 * time.Hour)
+		if err := dao.DB.Model(&model.Temporary{}).Where(map[string]interface{}{"key": paste.Key}).
+			Update("created_at", createdAt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 指定的过期时刻同样从创建时开始计算
+	expireAt := time.Now().Add(time.Hour)
+	if recorder := serve(Extend, http.MethodPatch, "/", params, "night-shift", ExpiryRequest{ExpireAt: &expireAt}); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expire_at beyond total lifetime: expect 403, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
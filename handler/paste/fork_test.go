@@ -0,0 +1,135 @@
+package paste
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/PasteUs/PasteMeGoBackend/model/migration"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if err := migration.Up(); err != nil {
+		panic(err)
+	}
+	fetchUser = func(accessToken string) (*OAuthUser, error) {
+		return &OAuthUser{ID: 1, Username: accessToken, TrustLevel: 3, Active: true}, nil
+	}
+	os.Exit(m.Run())
+}
+
+// serve 以 username 登陆发出请求，返回响应
+func serve(handler gin.HandlerFunc, method string, target string, params gin.Params, username string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+
+	recorder := httptest.NewRecorder()
+	context, _ := gin.CreateTestContext(recorder)
+	context.Request = httptest.NewRequest(method, target, reader)
+	context.Request.Header.Set("Content-Type", "application/json")
+	if username != "" {
+		context.Request.AddCookie(&http.Cookie{Name: "access_token", Value: username})
+	}
+	context.Params = params
+	handler(context)
+	return recorder
+}
+
+func TestFork(t *testing.T) {
+	source := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "go", Content: "package main", Password: "secret", Username: "alice"}}
+	if err := source.Save(); err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "key", Value: source.Key}}
+
+	if recorder := serve(Fork, http.MethodPost, "/", params, "bob", nil); recorder.Code != http.StatusForbidden {
+		t.Fatalf("fork without password: expect 403, got %d", recorder.Code)
+	}
+
+	recorder := serve(Fork, http.MethodPost, "/?password=secret", params, "bob", nil)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expect 201, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var response CreateResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+
+	fork := model.Permanent{AbstractPaste: &model.AbstractPaste{Key: response.Key}}
+	if err := fork.Get(""); err != nil {
+		t.Fatal(err)
+	}
+	if fork.Content != "package main" || fork.Lang != "go" || fork.ParentKey != source.Key || fork.Username != "bob" {
+		t.Errorf("unexpected fork %+v", fork.AbstractPaste)
+	}
+
+	forks, err := model.Forks(source.Key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forks) != 1 || forks[0].Key != response.Key {
+		t.Errorf("unexpected forks %+v", forks)
+	}
+}
+
+func TestForksOfProtectedSource(t *testing.T) {
+	source := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "db credentials", Password: "secret", Username: "vault-owner"}}
+	if err := source.Save(); err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "key", Value: source.Key}}
+	if recorder := serve(Fork, http.MethodPost, "/?password=secret", params, "vault-forker", nil); recorder.Code != http.StatusCreated {
+		t.Fatalf("fork: expect 201, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 未登陆时不能列出受保护来源的 fork
+	if recorder := serve(Forks, http.MethodGet, "/", params, "", nil); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: expect 401, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	for username, count := range map[string]int{"vault-forker": 1, "vault-stranger": 0} {
+		recorder := serve(Forks, http.MethodGet, "/", params, username, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: expect 200, got %d %s", username, recorder.Code, recorder.Body.String())
+		}
+		var response ForksResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+		if len(response.Forks) != count {
+			t.Errorf("%s: expect %d forks, got %+v", username, count, response.Forks)
+		}
+	}
+}
+
+func TestForkTemporary(t *testing.T) {
+	source := model.Temporary{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "one time"}, ExpireSecond: 60, ExpireCount: 2}
+	if err := source.Save(); err != nil {
+		t.Fatal(err)
+	}
+	params := gin.Params{{Key: "key", Value: source.Key}}
+
+	// 请求被拒绝时不会消耗查看次数
+	body := map[string]interface{}{"self_destruct": true, "expire_second": 60}
+	if recorder := serve(Fork, http.MethodPost, "/", params, "bob", body); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expect 400, got %d", recorder.Code)
+	}
+
+	body["expire_count"] = 1
+	if recorder := serve(Fork, http.MethodPost, "/", params, "bob", body); recorder.Code != http.StatusCreated {
+		t.Fatalf("expect 201, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	// fork 算作一次查看，剩余一次
+	got := model.Temporary{AbstractPaste: &model.AbstractPaste{Key: source.Key}}
+	if err := got.Get(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&model.Temporary{AbstractPaste: &model.AbstractPaste{Key: source.Key}}).Get(""); err == nil {
+		t.Error("source should be consumed")
+	}
+}
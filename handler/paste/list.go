@@ -0,0 +1,105 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// parseListOptions 解析列表的查询参数，不合法时返回 false
+func parseListOptions(context *gin.Context) (options model.ListOptions, ok bool) {
+	options.Lang = context.Query("lang")
+	options.Tag = context.Query("tag")
+	options.Type = context.Query("type")
+	options.Sort = context.DefaultQuery("sort", model.SortCreatedAt)
+	options.Cursor = context.Query("cursor")
+
+	if options.Type != "" && options.Type != model.TypePermanent && options.Type != model.TypeTemporary {
+		return options, false
+	}
+	if options.Sort != model.SortCreatedAt && options.Sort != model.SortSize {
+		return options, false
+	}
+	switch context.DefaultQuery("order", "desc") {
+	case "asc":
+		options.Ascending = true
+	case "desc":
+	default:
+		return options, false
+	}
+
+	var err error
+	if value := context.Query("limit"); value != "" {
+		if options.Limit, err = strconv.Atoi(value); err != nil || options.Limit < 1 || options.Limit > model.MaxListLimit {
+			return options, false
+		}
+	}
+	if value := context.Query("created_after"); value != "" {
+		if options.CreatedAfter, err = time.Parse(time.RFC3339, value); err != nil {
+			return options, false
+		}
+	}
+	if value := context.Query("created_before"); value != "" {
+		if options.CreatedBefore, err = time.Parse(time.RFC3339, value); err != nil {
+			return options, false
+		}
+	}
+	return options, true
+}
+
+// List godoc
+// @Summary 列出自己的一贴
+// @Description 列出当前用户创建的一贴，只包含元信息，不会消耗自我销毁的一贴的查看次数
+// @Description 返回的 next_cursor 作为 cursor 参数传入即可获取下一页
+// @Tags Paste
+// @Produce json
+// @Param lang query string false "语言"
+// @Param tag query string false "标签"
+// @Param type query string false "permanent 或 temporary，默认两者都列出"
+// @Param created_after query string false "创建时间不早于，RFC3339 格式"
+// @Param created_before query string false "创建时间早于，RFC3339 格式"
+// @Param sort query string false "created_at 或 size，默认为 created_at"
+// @Param order query string false "asc 或 desc，默认为 desc"
+// @Param limit query int false "每页数量，默认为 20，最大为 100"
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} ListResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/pastes [get]
+func List(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	options, ok := parseListOptions(context)
+	if !ok {
+		logging.Info("invalid query", zap.String("query", context.Request.URL.RawQuery))
+		common.ErrInvalidQuery.Abort(context)
+		return
+	}
+
+	pastes, next, err := model.List(user.Username, options)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidCursor) {
+			common.ErrInvalidQuery.Abort(context)
+			return
+		}
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+
+	common.JSON(context, ListResponse{
+		Response:   &common.Response{Code: http.StatusOK},
+		Pastes:     pastes,
+		NextCursor: next,
+	})
+}
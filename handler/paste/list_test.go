@@ -0,0 +1,31 @@
+package paste
+
+import (
+	"encoding/json"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"net/http"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	paste := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "hello", Username: "carol"}}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"type=draft", "sort=lang", "order=up", "limit=0", "limit=101", "created_after=yesterday", "cursor=%21"} {
+		if recorder := serve(List, http.MethodGet, "/?"+query, nil, "carol", nil); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expect 400, got %d", query, recorder.Code)
+		}
+	}
+
+	recorder := serve(List, http.MethodGet, "/?type=permanent", nil, "carol", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var response ListResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if len(response.Pastes) != 1 || response.Pastes[0].Key != paste.Key || response.Pastes[0].Size != 5 {
+		t.Errorf("unexpected pastes %+v", response.Pastes)
+	}
+}
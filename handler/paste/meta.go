@@ -0,0 +1,80 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+// writeMeta 读取并返回一贴的元信息，同时在响应头中给出自我销毁的一贴剩余的查看次数和过期时间
+func writeMeta(context *gin.Context, key string) {
+	meta, err := model.ReadMeta(key)
+	if err != nil {
+		abortWithError(context, "read meta", err)
+		return
+	}
+	if meta.RemainingViews != nil {
+		context.Header("X-Remaining-Views", strconv.FormatUint(*meta.RemainingViews, 10))
+		context.Header("Cache-Control", "no-store") // 剩余的查看次数随时会变化
+	}
+	if meta.ExpiresAt != nil {
+		context.Header("Expires", meta.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	common.JSON(context, MetaResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Meta:     meta,
+	})
+}
+
+// Meta godoc
+// @Summary 读取一贴的元信息
+// @Description 不返回内容、不需要密码，也不会消耗自我销毁的一贴的查看次数，可以在 reveal 之前展示给用户确认
+// @Description 自我销毁的一贴在 X-Remaining-Views 和 Expires 响应头中给出剩余的查看次数和过期时间，HEAD /paste/{key} 只返回响应头
+// @Tags Paste
+// @Produce json
+// @Param key path string true "索引"
+// @Success 200 {object} MetaResponse
+// @Header 200 {integer} X-Remaining-Views "剩余的查看次数，仅自我销毁的一贴"
+// @Header 200 {string} Expires "过期时间，永久的一贴为设置的删除时间"
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/meta [get]
+// @Router /paste/{key} [head]
+func Meta(context *gin.Context) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
+	}
+	writeMeta(context, key)
+}
+
+// Reveal godoc
+// @Summary 读取一贴
+// @Description 与 GET /paste/{key} 相同，但总是返回 JSON，用于用户确认后再读取自我销毁的一贴
+// @Description 链接预览机器人发出的请求会被拒绝，不会消耗查看次数
+// @Tags Paste
+// @Produce json
+// @Param key path string true "索引"
+// @Param password query string false "密码"
+// @Success 200 {object} GetResponse
+// @Success 200 {object} EncryptedGetResponse "客户端加密的 paste"
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/reveal [post]
+func Reveal(context *gin.Context) {
+	if isPreview(context) {
+		logging.Info("reveal by link preview", zap.String("user_agent", context.GetHeader("User-Agent")))
+		common.ErrPreviewAgent.Abort(context)
+		return
+	}
+
+	paste := load(context, "")
+	if paste == nil {
+		return
+	}
+	recordView(context, paste)
+	render(context, paste, true)
+}
@@ -0,0 +1,129 @@
+package paste
+
+import (
+	"encoding/json"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serveAs 以指定的 User-Agent 匿名发出请求
+func serveAs(handler gin.HandlerFunc, method string, key string, userAgent string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	context, _ := gin.CreateTestContext(recorder)
+	context.Request = httptest.NewRequest(method, "/", nil)
+	context.Request.Header.Set("User-Agent", userAgent)
+	context.Params = gin.Params{{Key: "key", Value: key}}
+	handler(context)
+	return recorder
+}
+
+func remainingViews(t *testing.T, key string) uint64 {
+	recorder := serveAs(Meta, http.MethodGet, key, "Mozilla/5.0")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("meta: expect 200, got %d", recorder.Code)
+	}
+	var response MetaResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Meta == nil || response.RemainingViews == nil {
+		t.Fatalf("unexpected meta %s", recorder.Body.String())
+	}
+	return *response.RemainingViews
+}
+
+func TestIsPreview(t *testing.T) {
+	for agent, expect := range map[string]bool{
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)":                              true,
+		"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)":                       true,
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)":               true,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Line/13.6.1": false, // LINE 内置浏览器是真实用户
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Teams/1.6.00 Chrome/114.0":  false,
+		"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0 PreviewExtension": false,
+		"": false,
+	} {
+		recorder := httptest.NewRecorder()
+		context, _ := gin.CreateTestContext(recorder)
+		context.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		context.Request.Header.Set("User-Agent", agent)
+		if got := isPreview(context); got != expect {
+			t.Errorf("%q: expect %v, got %v", agent, expect, got)
+		}
+	}
+}
+
+func TestPreviewSafeReveal(t *testing.T) {
+	paste := model.Temporary{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "burn after reading"}, ExpireSecond: 60, ExpireCount: 1}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if views := remainingViews(t, paste.Key); views != 1 {
+		t.Fatalf("expect 1 remaining view, got %d", views)
+	}
+	for _, agent := range []string{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", "TelegramBot (like TwitterBot)"} {
+		if recorder := serveAs(Get, http.MethodGet, paste.Key, agent); recorder.Code != http.StatusOK {
+			t.Errorf("%s: expect 200, got %d", agent, recorder.Code)
+		}
+		if recorder := serveAs(Reveal, http.MethodPost, paste.Key, agent); recorder.Code != http.StatusForbidden {
+			t.Errorf("%s: reveal expect 403, got %d", agent, recorder.Code)
+		}
+	}
+	if views := remainingViews(t, paste.Key); views != 1 {
+		t.Fatalf("preview consumed a view, %d remaining", views)
+	}
+
+	recorder := serveAs(Reveal, http.MethodPost, paste.Key, "Mozilla/5.0")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("reveal: expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var response GetResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Content != "burn after reading" {
+		t.Errorf("unexpected content %s", recorder.Body.String())
+	}
+	if recorder = serveAs(Meta, http.MethodGet, paste.Key, "Mozilla/5.0"); recorder.Code != http.StatusNotFound {
+		t.Errorf("meta after last view: expect 404, got %d", recorder.Code)
+	}
+}
+
+func TestMetaHeaders(t *testing.T) {
+	paste := model.Temporary{AbstractPaste: &model.AbstractPaste{Lang: "go", Content: "package main"}, ExpireSecond: 300, ExpireCount: 3}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		recorder := serveAs(Meta, method, paste.Key, "Mozilla/5.0")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: expect 200, got %d", method, recorder.Code)
+		}
+		if views := recorder.Header().Get("X-Remaining-Views"); views != "3" {
+			t.Errorf("%s: unexpected X-Remaining-Views %q", method, views)
+		}
+		expires, err := http.ParseTime(recorder.Header().Get("Expires"))
+		if err != nil || !expires.Equal(paste.ExpiresAt.Truncate(time.Second)) {
+			t.Errorf("%s: unexpected Expires %q", method, recorder.Header().Get("Expires"))
+		}
+	}
+
+	var response MetaResponse
+	_ = json.Unmarshal(serveAs(Meta, http.MethodGet, paste.Key, "Mozilla/5.0").Body.Bytes(), &response)
+	if response.Meta == nil || response.Lang != "go" || response.Size != int64(len("package main")) || response.HasPassword || response.CreatedAt.IsZero() {
+		t.Errorf("unexpected meta %+v", response.Meta)
+	}
+	if views := remainingViews(t, paste.Key); views != 3 {
+		t.Fatalf("meta consumed a view, %d remaining", views)
+	}
+
+	permanent := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "permanent"}}
+	if err := permanent.Save(); err != nil {
+		t.Fatal(err)
+	}
+	recorder := serveAs(Meta, http.MethodHead, permanent.Key, "Mozilla/5.0")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("X-Remaining-Views") != "" || recorder.Header().Get("Expires") != "" {
+		t.Errorf("permanent: unexpected response %d %v", recorder.Code, recorder.Header())
+	}
+}
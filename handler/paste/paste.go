@@ -1,7 +1,10 @@
 package paste
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"github.com/PasteUs/PasteMeGoBackend/common/logging"
 	"github.com/PasteUs/PasteMeGoBackend/handler/common"
 	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
@@ -14,12 +17,13 @@ import (
 
 // Create 创建一贴
 // @Summary 创建永久存储或者是自我销毁的一贴
-// @Description 只有在登陆的状态下才能创建永久的一贴
+// @Description 只有在登陆的状态下才能创建永久的一贴，二进制文件以 multipart/form-data 上传，表单字段见 UploadRequest
 // @Tags Paste
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Param Authorization header string false "登陆的 Token"
 // @Param data body CreateRequest true "请求数据"
+// @Param file formData file false "上传的二进制文件"
 // @Success 201 {object} CreateResponse
 // @Failure default {object} common.ErrorResponse
 // @Router /paste/ [post]
@@ -34,69 +38,186 @@ func Create(context *gin.Context) {
 
 	// 验证 access_token（调用 authenticator 函数或其他验证逻辑）
 	var requestBody CreateRequest
-	if err := context.ShouldBindJSON(&requestBody); err != nil {
+	if isMultipart(context) {
+		if err := bindUpload(context, &requestBody); err != nil {
+			logging.Warn("bind upload failed", zap.String("message", err.Message))
+			err.Abort(context)
+			return
+		}
+	} else if err := context.ShouldBindJSON(&requestBody); err != nil || requestBody.AbstractPaste == nil {
 		logging.Warn("bind body failed", zap.Error(err))
 		common.ErrWrongParamType.Abort(context)
 		return
 	}
 
+	if err := validator(requestBody); err != nil {
+		logging.Info("invalid request", zap.String("message", err.Message))
+		err.Abort(context)
+		return
+	}
+
 	// 鉴权逻辑，可以使用 authenticator 函数或者直接在此处验证
-	if err := authenticator(requestBody, accessToken); err != nil {
+	user, errorResponse := authenticator(requestBody, accessToken)
+	if errorResponse != nil {
 		logging.Info("unauthorized request")
-		err.Abort(context)
+		errorResponse.Abort(context)
 		return
 	}
+	requestBody.AbstractPaste.Username = user.Username
 
+	save(context, requestBody)
+}
+
+// save 按请求创建永久或者自我销毁的一贴，并返回创建的响应
+func save(context *gin.Context, requestBody CreateRequest) {
 	// 处理创建 Paste 的逻辑
 	var paste model.IPaste
 	if requestBody.SelfDestruct {
 		paste = &model.Temporary{
 			AbstractPaste: requestBody.AbstractPaste,
 			ExpireSecond:  requestBody.ExpireSecond,
+			ExpireAt:      requestBody.ExpireAt,
+			IdleSecond:    requestBody.IdleSecond,
 			ExpireCount:   requestBody.ExpireCount,
 		}
 	} else {
-		paste = &model.Permanent{AbstractPaste: requestBody.AbstractPaste}
+		requestBody.AbstractPaste.Key = requestBody.Key
+		paste = &model.Permanent{AbstractPaste: requestBody.AbstractPaste, DeleteAt: requestBody.DeleteAt}
 	}
 
 	if err := paste.Save(); err != nil {
 		logging.Error("save failed", zap.Error(err))
-		common.ErrSaveFailed.Abort(context)
+		if errorResponse, ok := err.(*common.ErrorResponse); ok {
+			errorResponse.Abort(context)
+		} else {
+			common.ErrSaveFailed.Abort(context)
+		}
 		return
 	}
 
 	// 返回成功响应
 	common.JSON(context, CreateResponse{
-		Response: &common.Response{Code: http.StatusCreated},
-		Key:      paste.GetKey(),
+		Response:    &common.Response{Code: http.StatusCreated},
+		Key:         paste.GetKey(),
+		DeleteToken: paste.GetDeleteToken(),
 	})
 }
 
 // Get godoc
 // @Summary 读取一贴
 // @Description 如果不指定 Accept: application/json 的话，默认会返回 text/plain 格式的 content
+// @Description 链接预览机器人读取自我销毁的一贴时只返回元信息，不会消耗查看次数
 // @Tags Paste
 // @Accept json
 // @Produce json
 // @Param Accept header string false "响应格式" default("text/plain")
+// @Param Accept-Encoding header string false "text/plain 格式下支持 gzip 时直接返回压缩后的内容"
 // @Param key path string true "索引"
-// @Success 201 {object} GetResponse
+// @Param rev query int false "修订号，只对永久的 paste 有效，默认为当前版本"
+// @Success 200 {object} GetResponse
+// @Success 200 {object} EncryptedGetResponse "客户端加密的 paste"
 // @Failure default {object} common.ErrorResponse
 // @Router /paste/{key} [get]
 func Get(context *gin.Context) {
-	key := strings.ToLower(context.Param("key"))
+	asJSON := strings.Contains(context.GetHeader("Accept"), "json")
+	acceptEncoding := ""
+	if !asJSON && acceptsEncoding(context.GetHeader("Accept-Encoding"), "gzip") {
+		acceptEncoding = "gzip" // 压缩后的内容可以原样返回，无需解压
+	}
+
+	paste := load(context, acceptEncoding)
+	if paste == nil {
+		return
+	}
+	recordView(context, paste)
+	render(context, paste, asJSON)
+}
+
+// render 按 Accept 返回读取到的一贴
+func render(context *gin.Context, paste model.IPaste, asJSON bool) {
+	if paste.IsEncrypted() {
+		// 无论 Accept 为何都返回 JSON，密文需要连同 IV 和盐一起交给客户端解密
+		common.JSON(context, EncryptedGetResponse{
+			Response:  &common.Response{Code: http.StatusOK},
+			Encrypted: true,
+			Lang:      paste.GetLang(),
+			Content:   paste.GetContent(),
+			IV:        paste.GetIV(),
+			Salt:      paste.GetSalt(),
+		})
+	} else if paste.IsBinary() && !asJSON {
+		serveBinary(context, paste) // 图片在 Accept 允许时直接显示，其它类型作为附件下载
+	} else if paste.IsBinary() {
+		common.JSON(context, GetResponse{
+			Response:  &common.Response{Code: http.StatusOK},
+			Lang:      paste.GetLang(),
+			Content:   base64.StdEncoding.EncodeToString(paste.GetData()),
+			MimeType:  paste.GetMimeType(),
+			FileName:  paste.GetFileName(),
+			ParentKey: paste.GetParentKey(),
+		})
+	} else if paste.IsBundle() {
+		// 多文件无论 Accept 为何都返回 JSON，单个文件可以通过 /paste/:key/:filename 读取
+		common.JSON(context, GetResponse{
+			Response:  &common.Response{Code: http.StatusOK},
+			Lang:      paste.GetLang(),
+			Files:     paste.GetFiles(),
+			ParentKey: paste.GetParentKey(),
+		})
+	} else if asJSON {
+		common.JSON(context, GetResponse{
+			Response: &common.Response{
+				Code: http.StatusOK,
+			},
+			Lang:      paste.GetLang(),
+			Content:   paste.GetContent(),
+			ParentKey: paste.GetParentKey(),
+		})
+	} else if encoding := paste.GetEncoding(); encoding != "" {
+		context.Header("Content-Encoding", encoding)
+		context.Header("Vary", "Accept-Encoding")
+		context.Data(http.StatusOK, "text/plain; charset=utf-8", paste.GetEncodedContent())
+	} else {
+		context.String(http.StatusOK, paste.GetContent())
+	}
+}
+
+// load 按 key 和 rev 参数读取一贴，失败时已经写入错误响应并返回 nil
+func load(context *gin.Context, acceptEncoding string) model.IPaste {
+	key := model.NormalizeKey(context.Param("key"))
 
 	var paste model.IPaste
 
 	if err := keyValidator(key); err != nil {
 		err.Abort(context)
-		return
+		return nil
 	}
 
-	abstractPaste := model.AbstractPaste{Key: key}
+	abstractPaste := model.AbstractPaste{Key: key, AcceptEncoding: acceptEncoding}
 
-	if []rune(key)[0] == '0' {
+	revision, errorResponse := parseRevision(context.Query("rev"))
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return nil
+	}
+
+	temporary, errorResponse := temporaryKey(key)
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return nil
+	}
+	if temporary {
+		if revision != 0 {
+			common.ErrInvalidRevision.Abort(context) // Temporary 没有修订记录
+			return nil
+		}
+		if isPreview(context) {
+			writeMeta(context, key) // 链接预览只能看到元信息，不会消耗查看次数
+			return nil
+		}
 		paste = &model.Temporary{AbstractPaste: &abstractPaste}
+	} else if revision != 0 {
+		paste = &model.PasteRevision{AbstractPaste: &abstractPaste, Revision: revision}
 	} else {
 		paste = &model.Permanent{AbstractPaste: &abstractPaste}
 	}
@@ -114,18 +235,471 @@ func Get(context *gin.Context) {
 		}
 
 		errorResponse.Abort(context)
+		return nil
+	}
+
+	if revisioned, ok := paste.(interface{ GetRevision() uint }); ok {
+		context.Header("ETag", etag(revisioned.GetRevision()))
+	}
+	return paste
+}
+
+// GetFile godoc
+// @Summary 读取多文件 paste 中的一个文件
+// @Description 以 text/plain 格式返回文件的原始内容，自我销毁的一贴同样会消耗一次查看次数
+// @Tags Paste
+// @Produce plain
+// @Param key path string true "索引"
+// @Param filename path string true "文件名"
+// @Param password query string false "密码"
+// @Param rev query int false "修订号"
+// @Success 200 {string} string
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/{filename} [get]
+func GetFile(context *gin.Context) {
+	paste := load(context, "")
+	if paste == nil {
+		return
+	}
+	file, ok := paste.GetFile(context.Param("filename"))
+	if !ok {
+		common.ErrFileNotFound.Abort(context)
 		return
 	}
+	recordView(context, paste)
+	context.String(http.StatusOK, file.Content)
+}
 
-	if strings.Contains(context.GetHeader("Accept"), "json") {
-		common.JSON(context, GetResponse{
-			Response: &common.Response{
-				Code: http.StatusOK,
-			},
-			Lang:    paste.GetLang(),
-			Content: paste.GetContent(),
-		})
+// Download godoc
+// @Summary 打包下载多文件 paste
+// @Description 把全部文件打包为 zip 或 tar，单文件的 paste 打包为一个以 key 命名的文件
+// @Tags Paste
+// @Produce octet-stream
+// @Param key path string true "索引"
+// @Param format query string false "打包格式，zip 或 tar" default(zip)
+// @Param password query string false "密码"
+// @Param rev query int false "修订号"
+// @Success 200 {file} file
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/download [get]
+func Download(context *gin.Context) {
+	format := context.DefaultQuery("format", "zip")
+	write, ok := archivers[format]
+	if !ok {
+		common.ErrInvalidArchiveFormat.Abort(context)
+		return
+	}
+
+	paste := load(context, "")
+	if paste == nil {
+		return
+	}
+	if paste.IsEncrypted() {
+		common.ErrEncryptedBundle.Abort(context) // 密文只能由客户端解密
+		return
+	}
+
+	files := paste.GetFiles()
+	if paste.IsBinary() {
+		name := paste.GetFileName()
+		if name == "" {
+			name = paste.GetKey()
+		}
+		files = []model.File{{Name: name, Lang: paste.GetLang(), Content: string(paste.GetData())}}
+	} else if !paste.IsBundle() {
+		files = []model.File{{Name: paste.GetKey() + ".txt", Lang: paste.GetLang(), Content: paste.GetContent()}}
+	}
+
+	var buffer bytes.Buffer
+	if err := write(&buffer, files); err != nil {
+		logging.Error("write archive failed", context, zap.Error(err))
+		common.ErrArchiveFailed.Abort(context)
+		return
+	}
+	recordView(context, paste)
+	context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", paste.GetKey(), format))
+	context.Data(http.StatusOK, archiveContentType[format], buffer.Bytes())
+}
+
+// Edit godoc
+// @Summary 修改一贴
+// @Description 只有创建者可以修改永久的一贴，If-Match 需要为当前版本的 ETag，修改前的版本保存为修订记录
+// @Tags Paste
+// @Accept json
+// @Produce json
+// @Param If-Match header string true "当前版本的 ETag"
+// @Param key path string true "索引"
+// @Param data body EditRequest true "请求数据"
+// @Success 200 {object} EditResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key} [put]
+func Edit(context *gin.Context) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
+	}
+	temporary, errorResponse := temporaryKey(key)
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return
+	}
+	if temporary {
+		common.ErrNotEditable.Abort(context)
+		return
+	}
+
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	ifMatch := context.GetHeader("If-Match")
+	if ifMatch == "" {
+		common.ErrRevisionRequired.Abort(context)
+		return
+	}
+	revision, ok := parseETag(ifMatch)
+	if !ok {
+		common.ErrRevisionMismatch.Abort(context)
+		return
+	}
+
+	var requestBody EditRequest
+	if err := context.ShouldBindJSON(&requestBody); err != nil || requestBody.AbstractPaste == nil {
+		logging.Warn("bind body failed", zap.Error(err))
+		common.ErrWrongParamType.Abort(context)
+		return
+	}
+	if err := validator(CreateRequest{AbstractPaste: requestBody.AbstractPaste}); err != nil {
+		logging.Info("invalid request", zap.String("message", err.Message))
+		err.Abort(context)
+		return
+	}
+
+	requestBody.AbstractPaste.Key = key
+	paste := model.Permanent{AbstractPaste: requestBody.AbstractPaste}
+	if err := paste.Edit(user.Username, revision); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			errorResponse = common.ErrRecordNotFound
+		case errors.As(err, &errorResponse):
+		default:
+			logging.Error("edit failed", zap.String("key", key), zap.Error(err))
+			errorResponse = common.ErrSaveFailed
+		}
+		errorResponse.Abort(context)
+		return
+	}
+
+	context.Header("ETag", etag(paste.Revision))
+	common.JSON(context, EditResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Key:      paste.Key,
+		Revision: paste.Revision,
+	})
+}
+
+// Revisions godoc
+// @Summary 列出一贴的全部版本
+// @Description 只返回修订号、语言类型和创建时间，不包含内容
+// @Tags Paste
+// @Produce json
+// @Param key path string true "索引"
+// @Success 200 {object} RevisionsResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/revisions [get]
+func Revisions(context *gin.Context) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
+	}
+	temporary, errorResponse := temporaryKey(key)
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return
+	}
+	if temporary {
+		common.ErrRecordNotFound.Abort(context) // Temporary 没有修订记录
+		return
+	}
+
+	paste := model.Permanent{AbstractPaste: &model.AbstractPaste{Key: key}}
+	revisions, err := paste.Revisions()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ErrRecordNotFound.Abort(context)
+		} else {
+			logging.Error("query from db failed", context, zap.Error(err))
+			common.ErrQueryDBFailed.Abort(context)
+		}
+		return
+	}
+
+	context.Header("ETag", etag(paste.Revision))
+	common.JSON(context, RevisionsResponse{
+		Response:  &common.Response{Code: http.StatusOK},
+		Key:       key,
+		Revisions: revisions,
+	})
+}
+
+// Delete godoc
+// @Summary 删除一贴
+// @Description 携带创建时返回的删除凭证时，无需登陆即可立即彻底删除任意一贴
+// @Description 否则只有创建者和管理员可以删除永久的一贴，删除后进入回收站，保留期内可以恢复
+// @Tags Paste
+// @Produce json
+// @Param X-Delete-Token header string false "删除凭证"
+// @Param key path string true "索引"
+// @Success 200 {object} common.Response
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key} [delete]
+func Delete(context *gin.Context) {
+	if token := context.GetHeader("X-Delete-Token"); token != "" {
+		revoke(context, token)
+		return
+	}
+	ownerAction(context, "delete", func(paste *model.Permanent, user *OAuthUser) error {
+		return paste.Remove(user.Username, isAdmin(user))
+	})
+}
+
+// Restore godoc
+// @Summary 恢复一贴
+// @Description 只有创建者和管理员可以恢复回收站中的一贴，超过保留期后不能恢复
+// @Tags Paste
+// @Produce json
+// @Param key path string true "索引"
+// @Success 200 {object} common.Response
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/restore [post]
+func Restore(context *gin.Context) {
+	ownerAction(context, "restore", func(paste *model.Permanent, user *OAuthUser) error {
+		return paste.Restore(user.Username, isAdmin(user))
+	})
+}
+
+// revoke 使用删除凭证删除一贴
+func revoke(context *gin.Context, token string) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
+	}
+
+	abstractPaste := model.AbstractPaste{Key: key}
+	var err error
+	temporary, errorResponse := temporaryKey(key)
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return
+	}
+	if temporary {
+		err = (&model.Temporary{AbstractPaste: &abstractPaste}).Revoke(token)
 	} else {
-		context.String(http.StatusOK, paste.GetContent())
+		err = (&model.Permanent{AbstractPaste: &abstractPaste}).Revoke(token)
+	}
+	if err != nil {
+		var errorResponse *common.ErrorResponse
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			errorResponse = common.ErrRecordNotFound
+		case errors.As(err, &errorResponse):
+		default:
+			logging.Error("revoke failed", zap.String("key", key), zap.Error(err))
+			errorResponse = common.ErrSaveFailed
+		}
+		errorResponse.Abort(context)
+		return
+	}
+
+	logging.Info("revoke paste", zap.String("key", key))
+	common.JSON(context, &common.Response{Code: http.StatusOK})
+}
+
+// ownerAction 校验 key 与登陆状态后对永久的一贴执行 action
+func ownerAction(context *gin.Context, name string, action func(paste *model.Permanent, user *OAuthUser) error) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
+	}
+	temporary, errorResponse := temporaryKey(key)
+	if errorResponse != nil {
+		errorResponse.Abort(context)
+		return
+	}
+	if temporary {
+		common.ErrNotPermanent.Abort(context)
+		return
+	}
+
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	if err := action(&model.Permanent{AbstractPaste: &model.AbstractPaste{Key: key}}, user); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			errorResponse = common.ErrRecordNotFound
+		case errors.As(err, &errorResponse):
+		default:
+			logging.Error(name+" failed", zap.String("key", key), zap.Error(err))
+			errorResponse = common.ErrSaveFailed
+		}
+		errorResponse.Abort(context)
+		return
+	}
+
+	logging.Info(name+" paste", zap.String("key", key), zap.String("username", user.Username))
+	common.JSON(context, &common.Response{Code: http.StatusOK})
+}
+
+// Trash godoc
+// @Summary 回收站
+// @Description 列出当前用户删除后仍可以恢复的永久的一贴，不包含内容
+// @Tags Paste
+// @Produce json
+// @Success 200 {object} TrashResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/trash [get]
+func Trash(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	pastes, err := model.Trash(user.Username)
+	if err != nil {
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+
+	common.JSON(context, TrashResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Pastes:   pastes,
+	})
+}
+
+// Fork godoc
+// @Summary Fork 一贴
+// @Description 以来源的内容创建新的一贴，请求中的内容会覆盖来源的内容，其余规则与创建时相同
+// @Description 来源设置了密码时需要提供 password，fork 自我销毁的一贴会消耗一次查看次数
+// @Tags Paste
+// @Accept json
+// @Produce json
+// @Param key path string true "来源的索引"
+// @Param password query string false "来源的密码"
+// @Param rev query int false "来源的修订号"
+// @Param data body CreateRequest false "请求数据，为空时 fork 为永久的一贴"
+// @Success 201 {object} CreateResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/fork [post]
+func Fork(context *gin.Context) {
+	accessToken, err := context.Cookie("access_token")
+	if err != nil || accessToken == "" {
+		logging.Info("missing access token in cookie, unauthorized")
+		common.ErrUnauthorized.Abort(context)
+		return
+	}
+
+	var requestBody CreateRequest
+	if context.Request.ContentLength != 0 {
+		if err := context.ShouldBindJSON(&requestBody); err != nil {
+			logging.Warn("bind body failed", zap.Error(err))
+			common.ErrWrongParamType.Abort(context)
+			return
+		}
+	}
+	if requestBody.AbstractPaste == nil {
+		requestBody.AbstractPaste = &model.AbstractPaste{}
+	}
+
+	// 读取来源之前先完成其余校验，避免请求被拒绝时白白消耗自我销毁的一贴的查看次数
+	if err := optionValidator(requestBody); err != nil {
+		logging.Info("invalid request", zap.String("message", err.Message))
+		err.Abort(context)
+		return
+	}
+	user, errorResponse := authenticator(requestBody, accessToken)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	source := load(context, "")
+	if source == nil {
+		return
+	}
+	context.Header("ETag", "") // ETag 属于来源，不属于新建的一贴
+	fillFromSource(requestBody.AbstractPaste, source)
+
+	if err := contentValidator(requestBody); err != nil {
+		logging.Info("invalid request", zap.String("message", err.Message))
+		err.Abort(context)
+		return
+	}
+
+	requestBody.AbstractPaste.Username = user.Username
+	requestBody.AbstractPaste.ParentKey = source.GetKey()
+	save(context, requestBody)
+}
+
+// Forks godoc
+// @Summary 列出一贴的 fork
+// @Description 只列出永久的 fork，不包含内容
+// @Description 来源设置了密码或已经不存在时需要登陆，只列出自己的 fork，避免通过列表找到受保护内容的副本
+// @Tags Paste
+// @Produce json
+// @Param key path string true "索引"
+// @Success 200 {object} ForksResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /paste/{key}/forks [get]
+func Forks(context *gin.Context) {
+	key := model.NormalizeKey(context.Param("key"))
+	if err := keyValidator(key); err != nil {
+		err.Abort(context)
+		return
 	}
+
+	var username string
+	meta, err := model.ReadMeta(key)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+	if err != nil || meta.HasPassword {
+		user, errorResponse := currentUser(context)
+		if errorResponse != nil {
+			logging.Info("unauthorized request")
+			errorResponse.Abort(context)
+			return
+		}
+		username = user.Username
+	}
+
+	forks, err := model.Forks(key, username)
+	if err != nil {
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+
+	common.JSON(context, ForksResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Key:      key,
+		Forks:    forks,
+	})
 }
@@ -0,0 +1,60 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Search godoc
+// @Summary 搜索自己的一贴
+// @Description 在当前用户的一贴中全文搜索，返回匹配附近的片段与高亮位置
+// @Description 设置了密码和自我销毁的一贴只有创建时指定 searchable 才能被搜索到，搜索不会消耗查看次数
+// @Tags Paste
+// @Produce json
+// @Param q query string true "关键词，以空格分隔，需要全部匹配"
+// @Param limit query int false "返回数量，默认为 20，最大为 100"
+// @Success 200 {object} SearchResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/pastes/search [get]
+func Search(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	query := strings.TrimSpace(context.Query("q"))
+	limit := model.DefaultSearchLimit
+	if value := context.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > model.MaxSearchLimit {
+			logging.Info("invalid limit", zap.String("limit", value))
+			common.ErrInvalidQuery.Abort(context)
+			return
+		}
+	}
+	if query == "" {
+		logging.Info("invalid query", zap.String("query", context.Request.URL.RawQuery))
+		common.ErrInvalidQuery.Abort(context)
+		return
+	}
+
+	results, err := model.Search(user.Username, query, limit)
+	if err != nil {
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+
+	common.JSON(context, SearchResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Results:  results,
+	})
+}
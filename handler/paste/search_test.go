@@ -0,0 +1,34 @@
+package paste
+
+import (
+	"encoding/json"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"net/http"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	paste := model.Permanent{AbstractPaste: &model.AbstractPaste{Lang: "plain", Content: "location / { proxy_pass http://backend; }", Username: "dave"}}
+	if err := paste.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"", "q=+", "q=proxy&limit=0", "q=proxy&limit=abc"} {
+		if recorder := serve(Search, http.MethodGet, "/?"+query, nil, "dave", nil); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expect 400, got %d", query, recorder.Code)
+		}
+	}
+	if recorder := serve(Search, http.MethodGet, "/?q=proxy", nil, "", nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expect 401, got %d", recorder.Code)
+	}
+
+	recorder := serve(Search, http.MethodGet, "/?q=proxy_pass", nil, "dave", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var response SearchResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if len(response.Results) != 1 || response.Results[0].Key != paste.Key || len(response.Results[0].Highlights) != 1 {
+		t.Errorf("unexpected results %+v", response.Results)
+	}
+}
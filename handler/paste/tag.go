@@ -0,0 +1,143 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// GetTags godoc
+// @Summary 读取一贴的标签
+// @Description 标签只对打上标签的用户可见
+// @Tags Paste
+// @Produce json
+// @Param key path string true "索引"
+// @Success 200 {object} TagsResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/pastes/{key}/tags [get]
+func GetTags(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	key := model.NormalizeKey(context.Param("key"))
+	tags, err := model.GetTags(key, user.Username)
+	if err != nil {
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+
+	common.JSON(context, TagsResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Key:      key,
+		Tags:     tags,
+	})
+}
+
+// SetTags godoc
+// @Summary 设置一贴的标签
+// @Description 替换一贴的全部标签，只有创建者可以设置，标签会转为小写并去重，传入空列表即可清除
+// @Tags Paste
+// @Accept json
+// @Produce json
+// @Param key path string true "索引"
+// @Param request body TagsRequest true "标签"
+// @Success 200 {object} TagsResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/pastes/{key}/tags [put]
+func SetTags(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	var requestBody TagsRequest
+	if err := context.ShouldBindJSON(&requestBody); err != nil {
+		logging.Warn("bind body failed", zap.Error(err))
+		common.ErrWrongParamType.Abort(context)
+		return
+	}
+
+	key := model.NormalizeKey(context.Param("key"))
+	tags, err := model.SetTags(key, user.Username, requestBody.Tags)
+	if err != nil {
+		abortWithError(context, "set tags", err)
+		return
+	}
+
+	common.JSON(context, TagsResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Key:      key,
+		Tags:     tags,
+	})
+}
+
+// UserTags godoc
+// @Summary 列出自己的标签
+// @Description 列出当前用户的全部标签以及使用它的一贴的数量，按名称排列
+// @Tags Paste
+// @Produce json
+// @Success 200 {object} UserTagsResponse
+// @Failure default {object} common.ErrorResponse
+// @Router /user/tags [get]
+func UserTags(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	tags, err := model.Tags(user.Username)
+	if err != nil {
+		logging.Error("query from db failed", context, zap.Error(err))
+		common.ErrQueryDBFailed.Abort(context)
+		return
+	}
+
+	common.JSON(context, UserTagsResponse{
+		Response: &common.Response{Code: http.StatusOK},
+		Tags:     tags,
+	})
+}
+
+// DeleteTag godoc
+// @Summary 删除标签
+// @Description 从当前用户的全部一贴上移除标签，一贴本身不受影响
+// @Tags Paste
+// @Produce json
+// @Param tag path string true "标签"
+// @Success 200 {object} common.Response
+// @Failure default {object} common.ErrorResponse
+// @Router /user/tags/{tag} [delete]
+func DeleteTag(context *gin.Context) {
+	user, errorResponse := currentUser(context)
+	if errorResponse != nil {
+		logging.Info("unauthorized request")
+		errorResponse.Abort(context)
+		return
+	}
+
+	tag := context.Param("tag")
+	count, err := model.DeleteTag(user.Username, tag)
+	if err != nil {
+		abortWithError(context, "delete tag", err)
+		return
+	}
+	if count == 0 {
+		common.ErrRecordNotFound.Abort(context)
+		return
+	}
+
+	logging.Info("delete tag", zap.String("tag", tag), zap.String("username", user.Username), zap.Int64("count", count))
+	common.JSON(context, &common.Response{Code: http.StatusOK})
+}
@@ -0,0 +1,153 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	uploadFormOverhead = 64 * 1024 // 表单中除文件以外的字段与分隔符
+	maxUploadFileName  = 255
+)
+
+// UploadRequest 以 multipart/form-data 上传二进制附件时的表单，字段含义与 CreateRequest 相同
+type UploadRequest struct {
+	File         *multipart.FileHeader `form:"file" binding:"required" swaggerignore:"true"`
+	Password     string                `form:"password"`
+	Key          string                `form:"key"`
+	SelfDestruct bool                  `form:"self_destruct"`
+	ExpireSecond uint64                `form:"expire_second"`
+	ExpireAt     time.Time             `form:"expire_at" time_format:"2006-01-02T15:04:05Z07:00"`
+	IdleSecond   uint64                `form:"idle_second"`
+	ExpireCount  uint64                `form:"expire_count"`
+	DeleteAt     time.Time             `form:"delete_at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+func isMultipart(context *gin.Context) bool {
+	return context.ContentType() == "multipart/form-data"
+}
+
+// bindUpload 解析 multipart/form-data 请求，读出上传的文件
+func bindUpload(context *gin.Context, body *CreateRequest) *common.ErrorResponse {
+	maxSize := int64(config.Config.Upload.MaxSize)
+	context.Request.Body = http.MaxBytesReader(context.Writer, context.Request.Body, maxSize+uploadFormOverhead)
+
+	var form UploadRequest
+	if err := context.ShouldBind(&form); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return common.ErrUploadTooLarge
+		}
+		return common.ErrWrongParamType
+	}
+	if form.File.Size > maxSize {
+		return common.ErrUploadTooLarge
+	}
+
+	file, err := form.File.Open()
+	if err != nil {
+		return common.ErrWrongParamType
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return common.ErrWrongParamType
+	}
+
+	paste := &model.AbstractPaste{Password: form.Password}
+	paste.SetData(data, sanitizeFileName(form.File.Filename))
+	*body = CreateRequest{
+		AbstractPaste: paste,
+		Key:           form.Key,
+		SelfDestruct:  form.SelfDestruct,
+		ExpireSecond:  form.ExpireSecond,
+		IdleSecond:    form.IdleSecond,
+		ExpireCount:   form.ExpireCount,
+	}
+	if !form.ExpireAt.IsZero() {
+		body.ExpireAt = &form.ExpireAt
+	}
+	if !form.DeleteAt.IsZero() {
+		body.DeleteAt = &form.DeleteAt
+	}
+	return nil
+}
+
+// sanitizeFileName 只保留文件名本身，去掉路径与控制字符
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > maxUploadFileName {
+		name = name[len(name)-maxUploadFileName:] // 保留扩展名
+	}
+	return name
+}
+
+// acceptsMediaType 判断 Accept 中是否允许 mediaType，没有 Accept 时允许任意类型
+func acceptsMediaType(header string, mediaType string) bool {
+	if strings.TrimSpace(header) == "" {
+		return true
+	}
+	category := strings.SplitN(mediaType, "/", 2)[0] + "/*"
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		if value := strings.TrimSpace(fields[0]); value != mediaType && value != category && value != "*/*" {
+			continue
+		}
+		for _, param := range fields[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				if value, err := strconv.ParseFloat(q[2:], 64); err == nil && value == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// inlineImage 图片在 Accept 允许时直接显示，svg 可以携带脚本，总是作为附件下载
+func inlineImage(mimeType string, accept string) bool {
+	return strings.HasPrefix(mimeType, "image/") && mimeType != "image/svg+xml" && acceptsMediaType(accept, mimeType)
+}
+
+// serveBinary 以检测到的 MIME 类型返回二进制附件
+func serveBinary(context *gin.Context, paste model.IPaste) {
+	disposition := "attachment"
+	if inlineImage(paste.GetMimeType(), context.GetHeader("Accept")) {
+		disposition = "inline"
+	}
+	fileName := paste.GetFileName()
+	if fileName == "" {
+		fileName = paste.GetKey()
+	}
+	if header := mime.FormatMediaType(disposition, map[string]string{"filename": fileName}); header != "" {
+		context.Header("Content-Disposition", header)
+	} else {
+		context.Header("Content-Disposition", disposition)
+	}
+	context.Header("X-Content-Type-Options", "nosniff")
+	context.Data(http.StatusOK, paste.GetMimeType(), paste.GetData())
+}
@@ -0,0 +1,107 @@
+package paste
+
+import (
+	"bytes"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/gin-gonic/gin"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func uploadContext(t *testing.T, fileName string, data []byte, fields map[string]string) *gin.Context {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write(data)
+	_ = writer.Close()
+
+	context, _ := gin.CreateTestContext(httptest.NewRecorder())
+	context.Request = httptest.NewRequest(http.MethodPost, "/api/v3/paste/", &buffer)
+	context.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return context
+}
+
+func TestBindUpload(t *testing.T) {
+	data := []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	context := uploadContext(t, `C:\Users\me\report.pdf`, data, map[string]string{
+		"password": "secret", "self_destruct": "true", "expire_second": "60", "expire_count": "1",
+	})
+	if !isMultipart(context) {
+		t.Fatal("expected multipart request")
+	}
+
+	var body CreateRequest
+	if err := bindUpload(context, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.MimeType != "application/pdf" || body.FileName != "report.pdf" || !bytes.Equal(body.Data, data) {
+		t.Errorf("unexpected upload %s %s", body.MimeType, body.FileName)
+	}
+	if body.Password != "secret" || !body.SelfDestruct || body.ExpireSecond != 60 || body.ExpireCount != 1 {
+		t.Errorf("unexpected form %+v", body)
+	}
+	if err := validator(body); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestBindUploadTooLarge(t *testing.T) {
+	maxSize := config.Config.Upload.MaxSize
+	config.Config.Upload.MaxSize = 16
+	defer func() {
+		config.Config.Upload.MaxSize = maxSize
+	}()
+
+	var body CreateRequest
+	if err := bindUpload(uploadContext(t, "a.bin", make([]byte, 17), nil), &body); err != common.ErrUploadTooLarge {
+		t.Errorf("expect %v, got %v", common.ErrUploadTooLarge, err)
+	}
+	if err := bindUpload(uploadContext(t, "a.bin", make([]byte, uploadFormOverhead+32), nil), &body); err != common.ErrUploadTooLarge {
+		t.Errorf("expect %v, got %v", common.ErrUploadTooLarge, err)
+	}
+}
+
+func TestInlineImage(t *testing.T) {
+	for _, c := range []struct {
+		mimeType string
+		accept   string
+		expect   bool
+	}{
+		{"image/png", "image/avif,image/webp,*/*", true},
+		{"image/png", "", true},
+		{"image/png", "image/*", true},
+		{"image/png", "application/json", false},
+		{"image/png", "image/png;q=0", false},
+		{"image/svg+xml", "*/*", false},
+		{"application/pdf", "*/*", false},
+	} {
+		if got := inlineImage(c.mimeType, c.accept); got != c.expect {
+			t.Errorf("%s with %q: expect %v, got %v", c.mimeType, c.accept, c.expect, got)
+		}
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	for name, expect := range map[string]string{
+		"report.pdf":         "report.pdf",
+		"../../etc/passwd":   "passwd",
+		`C:\Users\me\a.png`:  "a.png",
+		"evil\"\r\nname.txt": "evilname.txt",
+		"/":                  "",
+	} {
+		if got := sanitizeFileName(name); got != expect {
+			t.Errorf("%q: expect %q, got %q", name, expect, got)
+		}
+	}
+}
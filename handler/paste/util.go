@@ -1,13 +1,22 @@
 package paste
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
 	"github.com/PasteUs/PasteMeGoBackend/common/logging"
 	"github.com/PasteUs/PasteMeGoBackend/handler/common"
 	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"io"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,26 +30,191 @@ type OAuthUser struct {
 }
 
 var (
-	validLang  = []string{"plain", "cpp", "java", "python", "bash", "markdown", "json", "go"}
-	keyPattern = regexp.MustCompile("^[0-9a-z]{8}$")
+	validLang = []string{"plain", "cpp", "java", "python", "bash", "markdown", "json", "go"}
+	// reservedKey 保留的 key，避免与现有或以后的路由、页面混淆
+	reservedKey = []string{
+		"about", "admin", "api", "auth", "collection", "collections", "delete", "docs", "edit", "fork", "forks",
+		"help", "login", "logout", "meta", "new", "oauth", "paste", "pastes", "raw", "restore", "reveal",
+		"revisions", "search", "settings", "static", "stats", "tag", "tags", "token", "trash", "user", "users",
+	}
+	// reservedFileName 与 /paste/:key/ 下的路由重名的文件名
+	reservedFileName = []string{"download", "fork", "forks", "meta", "reveal", "revisions", "restore", "stats"}
+	fileNamePattern  = regexp.MustCompile(`^[0-9A-Za-z._-]+$`)
 )
 
+const maxFileNameLength = 64
+
 type CreateRequest struct {
 	*model.AbstractPaste
-	SelfDestruct bool   `json:"self_destruct" example:"true"` // 是否自我销毁
-	ExpireSecond uint64 `json:"expire_second" example:"300"`  // 创建若干秒后自我销毁
-	ExpireCount  uint64 `json:"expire_count" example:"1"`     // 访问若干次后自我销毁
+	Key          string     `json:"key" example:"deploy-notes"`                    // 自定义 key，仅永久 paste 可用
+	SelfDestruct bool       `json:"self_destruct" example:"true"`                  // 是否自我销毁
+	ExpireSecond uint64     `json:"expire_second" example:"300"`                   // 创建若干秒后自我销毁
+	ExpireAt     *time.Time `json:"expire_at" example:"2024-06-30T18:00:00+08:00"` // 在指定时刻自我销毁，不能与 expire_second 同时使用
+	IdleSecond   uint64     `json:"idle_second" example:"2592000"`                 // 若干秒没有被查看则自我销毁，每次查看后重新计时
+	ExpireCount  uint64     `json:"expire_count" example:"1"`                      // 访问若干次后自我销毁
+	DeleteAt     *time.Time `json:"delete_at" example:"2024-12-31T00:00:00+08:00"` // 永久的一贴在指定时刻移入回收站
 }
 
 type CreateResponse struct {
 	*common.Response
-	Key string `json:"key" example:"a1b2c3d4"`
+	Key         string `json:"key" example:"a1b2c3d4"`
+	DeleteToken string `json:"delete_token" example:"q3J0Y2xmZ1h4..."` // 删除凭证，只返回一次，放在 X-Delete-Token 中即可删除
 }
 
 type GetResponse struct {
 	*common.Response
-	Lang    string `json:"lang" example:"plain"`
-	Content string `json:"content" example:"Hello World!"`
+	Lang      string       `json:"lang" example:"plain"`
+	Content   string       `json:"content" example:"Hello World!"`
+	Files     []model.File `json:"files,omitempty"`      // 多文件 paste 的文件列表，此时 Lang 为 bundle，Content 为空
+	MimeType  string       `json:"mime_type,omitempty"`  // 二进制附件的 MIME 类型，此时 Lang 为 binary，Content 为 base64 编码的内容
+	FileName  string       `json:"filename,omitempty"`   // 二进制附件的原始文件名
+	ParentKey string       `json:"parent_key,omitempty"` // fork 的来源
+}
+
+type EditRequest struct {
+	*model.AbstractPaste
+}
+
+type EditResponse struct {
+	*common.Response
+	Key      string `json:"key" example:"a1b2c3d4"`
+	Revision uint   `json:"revision" example:"2"`
+}
+
+type TrashResponse struct {
+	*common.Response
+	Pastes []model.TrashInfo `json:"pastes"`
+}
+
+type ListResponse struct {
+	*common.Response
+	Pastes     []model.PasteInfo `json:"pastes"`
+	NextCursor string            `json:"next_cursor,omitempty"` // 为空时表示没有下一页
+}
+
+type SearchResponse struct {
+	*common.Response
+	Results []model.SearchResult `json:"results"`
+}
+
+type TagsRequest struct {
+	Tags []string `json:"tags" example:"incident-42,nginx"`
+}
+
+type TagsResponse struct {
+	*common.Response
+	Key  string   `json:"key" example:"a1b2c3d4"`
+	Tags []string `json:"tags"`
+}
+
+type UserTagsResponse struct {
+	*common.Response
+	Tags []model.TagInfo `json:"tags"`
+}
+
+type CollectionRequest struct {
+	Name string   `json:"name" example:"incident-42"`
+	Keys []string `json:"keys" example:"a1b2c3d4,deploy-notes"` // 按顺序排列的永久 paste
+}
+
+type CollectionResponse struct {
+	*common.Response
+	*model.Collection
+}
+
+type CollectionsResponse struct {
+	*common.Response
+	Collections []model.CollectionInfo `json:"collections"`
+}
+
+type MetaResponse struct {
+	*common.Response
+	*model.Meta
+}
+
+type ExpiryRequest struct {
+	ExpireSecond uint64     `json:"expire_second" example:"86400"`                 // 从现在起若干秒后自我销毁，不能与 expire_at 同时使用
+	ExpireAt     *time.Time `json:"expire_at" example:"2024-06-30T18:00:00+08:00"` // 新的过期时刻
+	IdleSecond   uint64     `json:"idle_second" example:"2592000"`                 // 新的闲置过期时间，只对设置了闲置过期的一贴有效
+	ExpireCount  uint64     `json:"expire_count" example:"10"`                     // 新的剩余查看次数
+	Permanent    bool       `json:"permanent" example:"false"`                     // 转为永久的一贴，对永久的一贴则是取消删除时间
+	DeleteAt     *time.Time `json:"delete_at" example:"2024-12-31T00:00:00+08:00"` // 永久的一贴的删除时间
+}
+
+type ExpiryResponse struct {
+	*common.Response
+	*model.Meta
+	Extensions []model.ExpiryExtension `json:"extensions"` // 全部的延长记录，按时间从旧到新排列
+}
+
+type StatsResponse struct {
+	*common.Response
+	Key string `json:"key" example:"a1b2c3d4"`
+	*model.ViewStats
+}
+
+type CacheStatsResponse struct {
+	*common.Response
+	model.CacheStats
+}
+
+type ForksResponse struct {
+	*common.Response
+	Key   string           `json:"key" example:"a1b2c3d4"`
+	Forks []model.ForkInfo `json:"forks"`
+}
+
+type RevisionsResponse struct {
+	*common.Response
+	Key       string               `json:"key" example:"a1b2c3d4"`
+	Revisions []model.RevisionInfo `json:"revisions"`
+}
+
+// EncryptedGetResponse 客户端加密的 paste 原样返回密文，由客户端使用 URL fragment 中的密钥解密
+type EncryptedGetResponse struct {
+	*common.Response
+	Encrypted bool   `json:"encrypted" example:"true"`
+	Lang      string `json:"lang" example:""`
+	Content   string `json:"content" example:"U2FsdGVkX1+..."` // base64 编码的密文
+	IV        string `json:"iv" example:"9M1xXvGqkJ0PqM1n"`
+	Salt      string `json:"salt" example:"x2TfIMa7T0ZUPOvZkM2yHQ"`
+}
+
+// etag 返回修订号对应的 ETag
+func etag(revision uint) string {
+	return fmt.Sprintf("\"%d\"", revision)
+}
+
+// parseETag 解析 If-Match 中的修订号，兼容弱校验的 W/ 前缀
+func parseETag(header string) (uint, bool) {
+	value := strings.Trim(strings.TrimPrefix(strings.TrimSpace(header), "W/"), "\"")
+	revision, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || revision == 0 {
+		return 0, false
+	}
+	return uint(revision), true
+}
+
+// parseRevision 解析 rev 参数，为空时返回 0
+func parseRevision(value string) (uint, *common.ErrorResponse) {
+	if value == "" {
+		return 0, nil
+	}
+	revision, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || revision == 0 {
+		return 0, common.ErrInvalidRevision
+	}
+	return uint(revision), nil
+}
+
+// temporaryKey 判断 key 是否属于自我销毁的一贴，查询失败时返回 common.ErrQueryDBFailed
+func temporaryKey(key string) (bool, *common.ErrorResponse) {
+	temporary, err := model.IsTemporaryKey(key)
+	if err != nil {
+		logging.Error("query paste type failed", zap.String("key", key), zap.Error(err))
+		return false, common.ErrQueryDBFailed
+	}
+	return temporary, nil
 }
 
 func contains(s []string, e string) bool {
@@ -52,7 +226,117 @@ func contains(s []string, e string) bool {
 	return false
 }
 
+// acceptsEncoding 判断 Accept-Encoding 中是否允许 encoding
+func acceptsEncoding(header string, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		if strings.TrimSpace(fields[0]) != encoding {
+			continue
+		}
+		for _, param := range fields[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				if value, err := strconv.ParseFloat(q[2:], 64); err == nil && value == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// encryptedValidator 客户端加密时内容为 base64 编码的密文，语言类型也可能一并加密，因此不做明文相关的检查
+func encryptedValidator(body CreateRequest) *common.ErrorResponse {
+	if body.Content == "" {
+		return common.ErrEmptyContent
+	}
+	if _, err := base64.StdEncoding.DecodeString(body.Content); err != nil {
+		return common.ErrInvalidEncryptedContent
+	}
+	if body.IV == "" || body.Salt == "" {
+		return common.ErrEmptyIVOrSalt
+	}
+	if len(body.IV) > 64 || len(body.Salt) > 64 || len(body.Lang) > 16 {
+		return common.ErrInvalidEncryptedContent
+	}
+	return nil
+}
+
+func vanityKeyValidator(body CreateRequest) *common.ErrorResponse {
+	if body.SelfDestruct {
+		return common.ErrCustomKeyForTemporary // 0 开头的 key 专属于 Temporary，自定义 key 不能用于自毁 paste
+	}
+	if !model.ValidVanityKey(body.Key) {
+		return common.ErrInvalidCustomKey
+	}
+	if contains(reservedKey, body.Key) {
+		return common.ErrReservedKey
+	}
+	return nil
+}
+
+// bundleValidator 校验多文件 paste 的文件名、语言类型和大小
+func bundleValidator(files []model.File) *common.ErrorResponse {
+	if len(files) > config.Config.Bundle.MaxFiles {
+		return common.ErrTooManyFiles
+	}
+	names := make(map[string]bool, len(files))
+	total := 0
+	for _, file := range files {
+		if !fileNamePattern.MatchString(file.Name) || len(file.Name) > maxFileNameLength ||
+			file.Name == "." || file.Name == ".." || contains(reservedFileName, file.Name) {
+			return common.ErrInvalidFileName
+		}
+		if names[file.Name] {
+			return common.ErrDuplicateFileName
+		}
+		names[file.Name] = true
+
+		if file.Content == "" {
+			return common.ErrEmptyContent
+		}
+		if file.Lang == "" {
+			return common.ErrEmptyLang
+		}
+		if !contains(validLang, file.Lang) {
+			return common.ErrInvalidLang
+		}
+		if len(file.Content) > config.Config.Bundle.MaxFileSize {
+			return common.ErrFileTooLarge
+		}
+		total += len(file.Content)
+	}
+	if total > config.Config.Bundle.MaxTotalSize {
+		return common.ErrBundleTooLarge
+	}
+	return nil
+}
+
 func validator(body CreateRequest) *common.ErrorResponse {
+	if err := contentValidator(body); err != nil {
+		return err
+	}
+	return optionValidator(body)
+}
+
+// contentValidator 校验内容
+func contentValidator(body CreateRequest) *common.ErrorResponse {
+	if body.Encrypted && len(body.Files) > 0 {
+		return common.ErrEncryptedBundle // 客户端加密的内容对服务端不透明，无法拆分为文件
+	}
+	if len(body.Files) > 0 {
+		return bundleValidator(body.Files)
+	}
+	if body.Encrypted {
+		return encryptedValidator(body)
+	}
+	if body.IsBinary() {
+		if len(body.Data) == 0 {
+			return common.ErrEmptyFile
+		}
+		return nil
+	}
+
 	if body.Content == "" {
 		return common.ErrEmptyContent // 内容为空，返回错误信息 "empty content"
 	}
@@ -62,23 +346,144 @@ func validator(body CreateRequest) *common.ErrorResponse {
 	if !contains(validLang, body.Lang) {
 		return common.ErrInvalidLang
 	}
+	return nil
+}
 
-	if body.SelfDestruct {
-		if body.ExpireSecond <= 0 {
-			return common.ErrZeroExpireSecond
+// optionValidator 校验自定义 key 与自我销毁的参数
+func optionValidator(body CreateRequest) *common.ErrorResponse {
+	if body.Key != "" {
+		if err := vanityKeyValidator(body); err != nil {
+			return err
 		}
-		if body.ExpireCount <= 0 {
-			return common.ErrZeroExpireCount
+	}
+
+	if !body.SelfDestruct {
+		if body.DeleteAt != nil && !body.DeleteAt.After(time.Now()) {
+			return common.ErrExpireTimeInPast
 		}
+		return nil
+	}
+
+	if body.DeleteAt != nil {
+		return common.ErrDeleteAtForTemporary
+	}
+	if body.ExpireSecond > 0 && body.ExpireAt != nil {
+		return common.ErrConflictingExpiry
+	}
+	if body.ExpireSecond <= 0 && body.ExpireAt == nil && body.IdleSecond <= 0 {
+		return common.ErrZeroExpireSecond // 至少需要一种过期时间
+	}
+	if body.ExpireCount <= 0 {
+		return common.ErrZeroExpireCount
+	}
 
-		if body.ExpireSecond > model.OneMonth {
-			return common.ErrExpireSecondGreaterThanMonth
+	if body.ExpireAt != nil && !body.ExpireAt.After(time.Now()) {
+		return common.ErrExpireTimeInPast
+	}
+	if lifetime(body) > model.OneMonth {
+		return common.ErrExpireSecondGreaterThanMonth
+	}
+	if body.ExpireCount > model.MaxCount {
+		return common.ErrExpireCountGreaterThanMaxCount
+	}
+	return nil
+}
+
+// lifetime 自我销毁的一贴在没有被查看的情况下最长可以保留的秒数
+// 闲置过期每次查看后重新计时，查看的次数由 ExpireCount 限制
+func lifetime(body CreateRequest) uint64 {
+	seconds := body.ExpireSecond
+	if body.ExpireAt != nil {
+		if until := time.Until(*body.ExpireAt); until > 0 {
+			seconds = uint64(until.Seconds())
 		}
-		if body.ExpireCount > model.MaxCount {
-			return common.ErrExpireCountGreaterThanMaxCount
+	}
+	if body.IdleSecond > seconds {
+		seconds = body.IdleSecond
+	}
+	return seconds
+}
+
+// expiryCeiling 各信任等级可以设置的查看次数和保留秒数的上限，0 表示只受全局上限的限制
+func expiryCeiling(trustLevel int) (count uint64, second uint64) {
+	switch trustLevel {
+	case 1:
+		return 50, 12 * 60 * 60
+	case 2:
+		return 100, 48 * 60 * 60
+	default:
+		return 0, 0 // 对于 trust_level >= 3 的用户，无限制
+	}
+}
+
+// fillFromSource 请求中没有内容时使用来源的内容，fork 时使用
+func fillFromSource(target *model.AbstractPaste, source model.IPaste) {
+	if target.Content != "" || len(target.Files) > 0 {
+		return
+	}
+	switch {
+	case source.IsEncrypted():
+		target.Encrypted, target.Content, target.IV, target.Salt = true, source.GetContent(), source.GetIV(), source.GetSalt()
+		target.Lang = source.GetLang()
+	case source.IsBinary():
+		target.SetData(source.GetData(), source.GetFileName())
+	case source.IsBundle():
+		target.Files = source.GetFiles()
+	default:
+		target.Content = source.GetContent()
+		if target.Lang == "" {
+			target.Lang = source.GetLang()
 		}
 	}
-	return nil
+}
+
+// fetchUser 获取用户信息，测试时可以替换
+var fetchUser = fetchOAuthUserInfo
+
+// currentUser 使用 Cookie 中的 access_token 获取当前用户
+func currentUser(context *gin.Context) (*OAuthUser, *common.ErrorResponse) {
+	accessToken, err := context.Cookie("access_token")
+	if err != nil || accessToken == "" {
+		return nil, common.ErrUnauthorized
+	}
+	user, err := fetchUser(accessToken)
+	if err != nil {
+		return nil, common.ErrUnauthorized
+	}
+	return user, nil
+}
+
+// abortWithError 按 model 返回的错误中止请求，未知的错误记录日志后返回 common.ErrSaveFailed
+func abortWithError(context *gin.Context, action string, err error) {
+	var errorResponse *common.ErrorResponse
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		errorResponse = common.ErrRecordNotFound
+	case errors.As(err, &errorResponse):
+	default:
+		logging.Error(action+" failed", zap.String("path", context.Request.URL.Path), zap.Error(err))
+		errorResponse = common.ErrSaveFailed
+	}
+	errorResponse.Abort(context)
+}
+
+// isPreview 判断请求是否来自聊天软件等的链接预览机器人
+func isPreview(context *gin.Context) bool {
+	userAgent := strings.ToLower(context.GetHeader("User-Agent"))
+	if userAgent == "" {
+		return false
+	}
+	for _, agent := range config.Config.Preview.UserAgents {
+		if agent != "" && strings.Contains(userAgent, strings.ToLower(agent)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdmin 判断用户是否为配置中的管理员
+func isAdmin(user *OAuthUser) bool {
+	return contains(config.Config.Admins, user.Username)
 }
 
 // fetchOAuthUserInfo 使用 accessToken 获取用户信息
@@ -119,54 +524,51 @@ func fetchOAuthUserInfo(accessToken string) (*OAuthUser, error) {
 }
 
 // authenticator 根据 OAuth 用户信息和请求参数进行鉴权
-func authenticator(body CreateRequest, accessToken string) *common.ErrorResponse {
+func authenticator(body CreateRequest, accessToken string) (*OAuthUser, *common.ErrorResponse) {
 	// 获取用户信息
-	user, err := fetchOAuthUserInfo(accessToken)
+	user, err := fetchUser(accessToken)
 	if err != nil {
-		return common.ErrUnauthorized
+		return nil, common.ErrUnauthorized
 	}
 
 	// 验证用户的 trust_level
 	if user.TrustLevel < 1 {
-		return common.ErrInsufficient_level // trust_level 小于 1 的用户无权限
+		return nil, common.ErrInsufficient_level // trust_level 小于 1 的用户无权限
+	}
+
+	// 自定义 key 的限制对所有用户生效，需要在其它提前返回之前检查
+	if body.Key != "" && user.TrustLevel < config.Config.Key.VanityTrustLevel {
+		return nil, common.ErrInsufficient_level
 	}
 
 	//开发者后门
 	if user.ID == 52042 {
 		logging.Info("Developer backdoor activated")
-		return nil
+		return user, nil
 	}
 
 	// 如果用户 trust_level 小于 3，则只能创建自毁请求
 	if user.TrustLevel < 3 && !body.SelfDestruct {
-		return common.ErrInsufficient_level
+		return nil, common.ErrInsufficient_level
 	}
 
-	// 对于启用自毁的请求，进一步检查限制条件
+	// 对于启用自毁的请求，根据不同的 trust_level 检查限制条件，指定时刻与闲置过期按最长的保留时间计算
 	if body.SelfDestruct {
-		// 根据不同的 trust_level 设置限制条件
-		switch user.TrustLevel {
-		case 1:
-			if body.ExpireCount > 50 || body.ExpireSecond > 12*60*60 {
-				return common.ErrInsufficient_level
-			}
-		case 2:
-			if body.ExpireCount > 100 || body.ExpireSecond > 48*60*60 {
-				return common.ErrInsufficient_level
-			}
-			// 对于 trust_level >= 3 的用户，无限制，不做额外检查
+		if count, second := expiryCeiling(user.TrustLevel); count > 0 &&
+			(body.ExpireCount > count || lifetime(body) > second) {
+			return nil, common.ErrInsufficient_level
 		}
 	}
 
-	// 鉴权通过，返回 nil 表示成功
-	return nil
+	// 鉴权通过，返回用户信息
+	return user, nil
 }
 
 func keyValidator(key string) *common.ErrorResponse {
-	if len(key) != 8 {
-		return common.ErrInvalidKeyLength // key's length should at least 3 and at most 8
+	if !model.ValidKeyLength(key) {
+		return common.ErrInvalidKeyLength // key 的长度应与配置或旧版本一致
 	}
-	if flag := keyPattern.MatchString(key); !flag {
+	if !model.ValidKeyFormat(key) {
 		return common.ErrInvalidKeyFormat
 	}
 	return nil
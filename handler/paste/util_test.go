@@ -0,0 +1,192 @@
+package paste
+
+import (
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	model "github.com/PasteUs/PasteMeGoBackend/model/paste"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncryptedValidator(t *testing.T) {
+	for name, c := range map[string]struct {
+		paste  model.AbstractPaste
+		expect *common.ErrorResponse
+	}{
+		"ok":             {model.AbstractPaste{Encrypted: true, Content: "aGVsbG8=", IV: "aXY=", Salt: "c2FsdA=="}, nil},
+		"no_lang_needed": {model.AbstractPaste{Encrypted: true, Content: "aGVsbG8=", IV: "aXY=", Salt: "c2FsdA==", Lang: ""}, nil},
+		"empty_content":  {model.AbstractPaste{Encrypted: true, IV: "aXY=", Salt: "c2FsdA=="}, common.ErrEmptyContent},
+		"not_base64":     {model.AbstractPaste{Encrypted: true, Content: "hello world", IV: "aXY=", Salt: "c2FsdA=="}, common.ErrInvalidEncryptedContent},
+		"empty_iv":       {model.AbstractPaste{Encrypted: true, Content: "aGVsbG8=", Salt: "c2FsdA=="}, common.ErrEmptyIVOrSalt},
+		"plaintext_lang": {model.AbstractPaste{Content: "hello world", Lang: "none"}, common.ErrInvalidLang},
+	} {
+		t.Run(name, func(t *testing.T) {
+			paste := c.paste
+			if err := validator(CreateRequest{AbstractPaste: &paste}); err != c.expect {
+				t.Errorf("expect %v, got %v", c.expect, err)
+			}
+		})
+	}
+}
+
+func TestVanityKeyValidator(t *testing.T) {
+	for name, c := range map[string]struct {
+		body   CreateRequest
+		expect *common.ErrorResponse
+	}{
+		"ok":             {CreateRequest{Key: "deploy-notes"}, nil},
+		"self_destruct":  {CreateRequest{Key: "deploy-notes", SelfDestruct: true}, common.ErrCustomKeyForTemporary},
+		"zero_prefix":    {CreateRequest{Key: "0deploy"}, common.ErrInvalidCustomKey},
+		"invalid_char":   {CreateRequest{Key: "deploy_notes"}, common.ErrInvalidCustomKey},
+		"too_long":       {CreateRequest{Key: "deploy-notes-2024"}, common.ErrInvalidCustomKey},
+		"reserved":       {CreateRequest{Key: "admin"}, common.ErrReservedKey},
+		"reserved_route": {CreateRequest{Key: "api"}, common.ErrReservedKey},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := vanityKeyValidator(c.body); err != c.expect {
+				t.Errorf("expect %v, got %v", c.expect, err)
+			}
+		})
+	}
+}
+
+func TestParseETag(t *testing.T) {
+	for header, expect := range map[string]uint{
+		`"3"`:   3,
+		`W/"3"`: 3,
+		`3`:     3,
+		`"0"`:   0,
+		`"abc"`: 0,
+		`*`:     0,
+	} {
+		revision, ok := parseETag(header)
+		if revision != expect || ok != (expect != 0) {
+			t.Errorf("%s: expect %d, got %d", header, expect, revision)
+		}
+	}
+	if header := etag(3); header != `"3"` {
+		t.Errorf("expect \"3\", got %s", header)
+	}
+}
+
+func TestBundleValidator(t *testing.T) {
+	file := func(name string, size int) model.File {
+		return model.File{Name: name, Lang: "plain", Content: strings.Repeat("a", size)}
+	}
+	maxFile := config.Config.Bundle.MaxFileSize
+	var tooLarge []model.File
+	for i := 0; i <= config.Config.Bundle.MaxTotalSize/maxFile; i++ {
+		tooLarge = append(tooLarge, file(fmt.Sprintf("%d.txt", i), maxFile))
+	}
+	for name, c := range map[string]struct {
+		files  []model.File
+		expect *common.ErrorResponse
+	}{
+		"ok":             {[]model.File{file("Dockerfile", 10), file("config.json", 10)}, nil},
+		"dot_file":       {[]model.File{file(".env", 10)}, nil},
+		"path":           {[]model.File{file("etc/passwd", 10)}, common.ErrInvalidFileName},
+		"parent":         {[]model.File{file("..", 10)}, common.ErrInvalidFileName},
+		"reserved":       {[]model.File{file("download", 10)}, common.ErrInvalidFileName},
+		"duplicate":      {[]model.File{file("a.txt", 10), file("a.txt", 10)}, common.ErrDuplicateFileName},
+		"empty_content":  {[]model.File{file("a.txt", 0)}, common.ErrEmptyContent},
+		"invalid_lang":   {[]model.File{{Name: "a.txt", Lang: "none", Content: "a"}}, common.ErrInvalidLang},
+		"file_too_large": {[]model.File{file("a.txt", maxFile+1)}, common.ErrFileTooLarge},
+		"too_large":      {tooLarge, common.ErrBundleTooLarge},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := bundleValidator(c.files); err != c.expect {
+				t.Errorf("expect %v, got %v", c.expect, err)
+			}
+		})
+	}
+
+	encrypted := model.AbstractPaste{Encrypted: true, Content: "aGVsbG8=", IV: "aXY=", Salt: "c2FsdA==", Files: []model.File{file("a.txt", 1)}}
+	if err := validator(CreateRequest{AbstractPaste: &encrypted}); err != common.ErrEncryptedBundle {
+		t.Errorf("expect %v, got %v", common.ErrEncryptedBundle, err)
+	}
+}
+
+func TestOptionValidator(t *testing.T) {
+	future, past := time.Now().Add(time.Hour), time.Now().Add(-time.Hour)
+	tooLate := time.Now().Add(time.Second * (model.OneMonth + 60))
+	for name, c := range map[string]struct {
+		body   CreateRequest
+		expect *common.ErrorResponse
+	}{
+		"expire_second":    {CreateRequest{SelfDestruct: true, ExpireSecond: 60, ExpireCount: 1}, nil},
+		"expire_at":        {CreateRequest{SelfDestruct: true, ExpireAt: &future, ExpireCount: 1}, nil},
+		"idle":             {CreateRequest{SelfDestruct: true, IdleSecond: 60, ExpireCount: 1}, nil},
+		"idle_capped":      {CreateRequest{SelfDestruct: true, IdleSecond: 60, ExpireAt: &future, ExpireCount: 1}, nil},
+		"no_expiry":        {CreateRequest{SelfDestruct: true, ExpireCount: 1}, common.ErrZeroExpireSecond},
+		"conflicting":      {CreateRequest{SelfDestruct: true, ExpireSecond: 60, ExpireAt: &future, ExpireCount: 1}, common.ErrConflictingExpiry},
+		"expire_at_past":   {CreateRequest{SelfDestruct: true, ExpireAt: &past, ExpireCount: 1}, common.ErrExpireTimeInPast},
+		"expire_at_month":  {CreateRequest{SelfDestruct: true, ExpireAt: &tooLate, ExpireCount: 1}, common.ErrExpireSecondGreaterThanMonth},
+		"idle_month":       {CreateRequest{SelfDestruct: true, IdleSecond: model.OneMonth + 1, ExpireCount: 1}, common.ErrExpireSecondGreaterThanMonth},
+		"delete_at":        {CreateRequest{DeleteAt: &future}, nil},
+		"delete_at_past":   {CreateRequest{DeleteAt: &past}, common.ErrExpireTimeInPast},
+		"delete_at_temp":   {CreateRequest{SelfDestruct: true, ExpireSecond: 60, ExpireCount: 1, DeleteAt: &future}, common.ErrDeleteAtForTemporary},
+		"zero_count":       {CreateRequest{SelfDestruct: true, IdleSecond: 60}, common.ErrZeroExpireCount},
+		"count_over_limit": {CreateRequest{SelfDestruct: true, IdleSecond: 60, ExpireCount: model.MaxCount + 1}, common.ErrExpireCountGreaterThanMaxCount},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := optionValidator(c.body); err != c.expect {
+				t.Errorf("expect %v, got %v", c.expect, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorExpiryCeiling(t *testing.T) {
+	origin := fetchUser
+	defer func() {
+		fetchUser = origin
+	}()
+	fetchUser = func(accessToken string) (*OAuthUser, error) {
+		level := map[string]int{"level1": 1, "level2": 2, "level3": 3}[accessToken]
+		return &OAuthUser{ID: 1, Username: accessToken, TrustLevel: level, Active: true}, nil
+	}
+
+	day := time.Now().Add(24 * time.Hour)
+	for name, c := range map[string]struct {
+		body   CreateRequest
+		token  string
+		expect *common.ErrorResponse
+	}{
+		"level1_ok":        {CreateRequest{SelfDestruct: true, ExpireSecond: 60 * 60, ExpireCount: 50}, "level1", nil},
+		"level1_count":     {CreateRequest{SelfDestruct: true, ExpireSecond: 60 * 60, ExpireCount: 51}, "level1", common.ErrInsufficient_level},
+		"level1_expire_at": {CreateRequest{SelfDestruct: true, ExpireAt: &day, ExpireCount: 1}, "level1", common.ErrInsufficient_level},
+		"level1_idle":      {CreateRequest{SelfDestruct: true, IdleSecond: 24 * 60 * 60, ExpireCount: 1}, "level1", common.ErrInsufficient_level},
+		"level2_expire_at": {CreateRequest{SelfDestruct: true, ExpireAt: &day, ExpireCount: 1}, "level2", nil},
+		"level2_idle":      {CreateRequest{SelfDestruct: true, IdleSecond: 72 * 60 * 60, ExpireCount: 1}, "level2", common.ErrInsufficient_level},
+		"level2_delete_at": {CreateRequest{DeleteAt: &day}, "level2", common.ErrInsufficient_level},
+		"level3_delete_at": {CreateRequest{DeleteAt: &day}, "level3", nil},
+		"level3_idle":      {CreateRequest{SelfDestruct: true, IdleSecond: model.OneMonth, ExpireCount: model.MaxCount}, "level3", nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := authenticator(c.body, c.token); err != c.expect {
+				t.Errorf("expect %v, got %v", c.expect, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorVanityKey(t *testing.T) {
+	origin := fetchUser
+	defer func() {
+		fetchUser = origin
+	}()
+	fetchUser = func(accessToken string) (*OAuthUser, error) {
+		return &OAuthUser{ID: 52042, Username: accessToken, TrustLevel: 1, Active: true}, nil
+	}
+
+	body := CreateRequest{Key: "myvanity", SelfDestruct: true, ExpireSecond: 60, ExpireCount: 1}
+	if _, err := authenticator(body, "developer"); err != common.ErrInsufficient_level {
+		t.Errorf("vanity key below trust level: expect %v, got %v", common.ErrInsufficient_level, err)
+	}
+	body.Key = ""
+	if _, err := authenticator(body, "developer"); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+}
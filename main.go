@@ -2,7 +2,13 @@ package main
 
 import (
 	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/common/flag"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/model/migration"
+	"github.com/PasteUs/PasteMeGoBackend/model/paste"
 	"github.com/PasteUs/PasteMeGoBackend/router"
+	"go.uber.org/zap"
+	"time"
 )
 
 // @title PasteMe API
@@ -21,5 +27,20 @@ import (
 // @BasePath /api/v3
 
 func main() {
+	if len(flag.Command) > 0 && flag.Command[0] == "migrate" {
+		migrate(flag.Command[1:])
+		return
+	}
+
+	if config.Config.AutoMigrate {
+		if err := migration.Up(); err != nil {
+			logging.Panic("migrate failed", zap.Error(err))
+		}
+	}
+
+	paste.StartSweeper(time.Duration(config.Config.Sweeper.Interval)*time.Second, config.Config.Sweeper.BatchSize)
+	paste.StartAnalytics(time.Duration(config.Config.Analytics.FlushInterval)*time.Second, config.Config.Analytics.BatchSize)
 	router.Run(config.Config.Address, config.Config.Port)
+	paste.StopAnalytics()
+	paste.StopSweeper()
 }
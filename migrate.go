@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/model/migration"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = "usage: pastemed [options] migrate up | down [steps] | status"
+
+// migrate 处理 pastemed migrate 子命令
+func migrate(args []string) {
+	if len(args) == 0 {
+		exit(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migration.Up(); err != nil {
+			exit(err.Error())
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				exit(migrateUsage)
+			}
+		}
+		if err := migration.Down(steps); err != nil {
+			exit(err.Error())
+		}
+	case "status":
+		statuses, err := migration.Statuses()
+		if err != nil {
+			exit(err.Error())
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			_, _ = fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		_ = writer.Flush()
+	default:
+		exit(migrateUsage)
+	}
+}
+
+func exit(message string) {
+	_, _ = fmt.Fprintln(os.Stderr, message)
+	os.Exit(1)
+}
@@ -7,11 +7,11 @@ import (
 	"github.com/PasteUs/PasteMeGoBackend/common/logging"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 	"os"
-	"reflect"
 )
 
 func format(
@@ -29,6 +29,35 @@ func formatWithConfig(database config.Database) string {
 	return format(database.Username, database.Password, "tcp", database.Server, database.Port, database.Database)
 }
 
+func formatPostgres(
+	username string,
+	password string,
+	server string,
+	port uint16,
+	database string,
+	sslMode string) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		server, port, username, password, database, sslMode)
+}
+
+func formatPostgresWithConfig(database config.Database) string {
+	sslMode := database.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	return formatPostgres(database.Username, database.Password, database.Server, database.Port, database.Database, sslMode)
+}
+
+// createPostgresDomains 让模型中 MySQL 风格的列类型在 PostgreSQL 上同样可用
+func createPostgresDomains() error {
+	return DB.Exec(`DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'mediumtext') THEN
+		CREATE DOMAIN mediumtext AS text;
+	END IF;
+END $$`).Error
+}
+
 var DB *gorm.DB
 
 func init() {
@@ -38,9 +67,27 @@ func init() {
 			NamingStrategy: schema.NamingStrategy{
 				SingularTable: true,
 			},
+			TranslateError: true, // 主键冲突统一转换为 gorm.ErrDuplicatedKey
 		}
 	)
-	if config.Config.Database.Type != "mysql" {
+	switch config.Config.Database.Type {
+	case "mysql":
+		if DB, err = gorm.Open(mysql.Open(formatWithConfig(config.Config.Database)), gormConfig); err != nil {
+			logging.Panic("connect to mysql failed", zap.Error(err))
+			return
+		}
+		logging.Info("mysql connected")
+	case "postgres":
+		if DB, err = gorm.Open(postgres.Open(formatPostgresWithConfig(config.Config.Database)), gormConfig); err != nil {
+			logging.Panic("connect to postgres failed", zap.Error(err))
+			return
+		}
+		if err = createPostgresDomains(); err != nil {
+			logging.Panic("create postgres domains failed", zap.Error(err))
+			return
+		}
+		logging.Info("postgres connected")
+	default:
 		sqlitePath := config.Config.Database.Database
 		pwd, _ := os.Getwd()
 		logging.Info("using sqlite", zap.String("database_type", config.Config.Database.Type), zap.String("work_dir", pwd))
@@ -48,13 +95,11 @@ func init() {
 			logging.Panic("sqlite connect failed", zap.String("sqlite_path", sqlitePath), zap.Error(err))
 			return
 		}
-		logging.Info("sqlite connect success", zap.String("sqlite_path", sqlitePath))
-	} else {
-		if DB, err = gorm.Open(mysql.Open(formatWithConfig(config.Config.Database)), gormConfig); err != nil {
-			logging.Panic("connect to mysql failed", zap.Error(err))
-			return
+		// sqlite 同一时间只允许一个写者，事务中读后写会直接返回 database is locked，因此只保留一个连接
+		if sqlDB, e := DB.DB(); e == nil {
+			sqlDB.SetMaxOpenConns(1)
 		}
-		logging.Info("mysql connected")
+		logging.Info("sqlite connect success", zap.String("sqlite_path", sqlitePath))
 	}
 	if flag.Debug {
 		logging.Warn("running in debug mode, database execute will be displayed")
@@ -62,28 +107,53 @@ func init() {
 	}
 }
 
-func getTableName(object interface{}) string {
-	var typeName string
-	if t := reflect.TypeOf(object); t.Kind() == reflect.Ptr {
-		typeName = t.Elem().Name()
-	} else {
-		typeName = t.Name()
+func getTableName(db *gorm.DB, object interface{}) string {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(object); err != nil {
+		return ""
+	}
+	return stmt.Schema.Table
+}
+
+// tableOptions 返回当前数据库建表时附加的选项，没有则返回空字符串
+func tableOptions() string {
+	switch config.Config.Database.Type {
+	case "mysql":
+		return "ENGINE=Innodb DEFAULT CHARSET=utf8mb4"
+	default:
+		return ""
 	}
-	return DB.NamingStrategy.TableName(typeName)
 }
 
-func CreateTable(object interface{}) {
-	db := DB
-	if config.Config.Database.Type == "mysql" {
-		db = db.Set("gorm:table_options", "ENGINE=Innodb DEFAULT CHARSET=utf8mb4")
+// CreateTable 在表不存在时创建，供 migration 使用
+func CreateTable(db *gorm.DB, object interface{}) error {
+	if options := tableOptions(); options != "" {
+		db = db.Set("gorm:table_options", options)
 	}
 	migrator := db.Migrator()
-	if !migrator.HasTable(object) {
-		tableName := zap.String("table_name", getTableName(object))
-		logging.Warn("Table not found, start creating", tableName)
+	if migrator.HasTable(object) {
+		return nil
+	}
+	logging.Warn("Table not found, start creating", zap.String("table_name", getTableName(db, object)))
+	return migrator.CreateTable(object)
+}
 
-		if err := migrator.CreateTable(object); err != nil {
-			logging.Panic("Create table failed", tableName, zap.Error(err))
-		}
+// AddColumn 为已存在的表补充新增的字段，字段已存在时什么也不做
+func AddColumn(db *gorm.DB, object interface{}, field string) error {
+	migrator := db.Migrator()
+	if migrator.HasColumn(object, field) {
+		return nil
+	}
+	logging.Warn("Column not found, start adding",
+		zap.String("table_name", getTableName(db, object)), zap.String("field", field))
+	return migrator.AddColumn(object, field)
+}
+
+// DropColumn 删除已存在的字段，供 migration 回滚使用
+func DropColumn(db *gorm.DB, object interface{}, field string) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(object, field) {
+		return nil
 	}
+	return migrator.DropColumn(object, field)
 }
@@ -0,0 +1,152 @@
+package migration
+
+import (
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// Migration 一次带版本号的表结构变更
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	Repair  func(tx *gorm.DB) error // 可选，每次启动时对已经执行过的 Migration 补齐依赖运行环境的部分
+}
+
+// SchemaMigration 记录已经执行过的 Migration
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(128)"`
+	AppliedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 单个 Migration 的执行状态
+type Status struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var migrations []Migration
+
+// register 注册一个 Migration，各版本在自己文件的 init 中调用
+func register(migration Migration) {
+	for _, m := range migrations {
+		if m.Version == migration.Version {
+			panic(fmt.Sprintf("duplicate migration version %d", migration.Version))
+		}
+	}
+	migrations = append(migrations, migration)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+func prepare() error {
+	return dao.CreateTable(dao.DB, &SchemaMigration{})
+}
+
+func applied() (map[uint]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := dao.DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]SchemaMigration, len(records))
+	for _, record := range records {
+		result[record.Version] = record
+	}
+	return result, nil
+}
+
+// Up 按版本号顺序执行所有尚未执行的 Migration
+func Up() error {
+	if err := prepare(); err != nil {
+		return err
+	}
+	done, err := applied()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; ok {
+			if m.Repair == nil {
+				continue
+			}
+			if err := dao.DB.Transaction(m.Repair); err != nil {
+				return fmt.Errorf("repair migration %d %s failed: %w", m.Version, m.Name, err)
+			}
+			continue
+		}
+		logging.Info("applying migration", zap.Uint("version", m.Version), zap.String("name", m.Name))
+		if err := dao.DB.Transaction(func(tx *gorm.DB) error {
+			if e := m.Up(tx); e != nil {
+				return e
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name}).Error
+		}); err != nil {
+			return fmt.Errorf("apply migration %d %s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个 Migration
+func Down(steps int) error {
+	if err := prepare(); err != nil {
+		return err
+	}
+	done, err := applied()
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		logging.Warn("reverting migration", zap.Uint("version", m.Version), zap.String("name", m.Name))
+		if err := dao.DB.Transaction(func(tx *gorm.DB) error {
+			if e := m.Down(tx); e != nil {
+				return e
+			}
+			return tx.Delete(&SchemaMigration{Version: m.Version}).Error
+		}); err != nil {
+			return fmt.Errorf("revert migration %d %s failed: %w", m.Version, m.Name, err)
+		}
+		steps--
+	}
+	return nil
+}
+
+// Statuses 返回所有已注册 Migration 的执行状态
+func Statuses() ([]Status, error) {
+	if err := prepare(); err != nil {
+		return nil, err
+	}
+	done, err := applied()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		record, ok := done[m.Version]
+		result = append(result, Status{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+	return result, nil
+}
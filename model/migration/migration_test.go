@@ -0,0 +1,100 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"testing"
+)
+
+func assertNil(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func assertApplied(t *testing.T, expect int) {
+	statuses, err := Statuses()
+	assertNil(t, err)
+	applied := 0
+	for _, status := range statuses {
+		if status.Applied {
+			applied++
+		}
+	}
+	if applied != expect {
+		t.Fatalf("expect %d applied migrations, got %d", expect, applied)
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	assertNil(t, Up())
+	assertApplied(t, len(migrations))
+
+	assertNil(t, Down(1))
+	assertApplied(t, len(migrations)-1)
+
+	assertNil(t, Down(len(migrations)))
+	assertApplied(t, 0)
+	if dao.DB.Migrator().HasTable(&permanentV1{}) {
+		t.Fatal("permanent not dropped")
+	}
+
+	assertNil(t, Up())
+	assertApplied(t, len(migrations))
+	if !dao.DB.Migrator().HasColumn(&temporaryV2{}, "ExpiresAt") {
+		t.Fatal("expires_at not created")
+	}
+}
+
+func TestUpIdempotent(t *testing.T) {
+	assertNil(t, Up())
+	assertNil(t, Up())
+	assertApplied(t, len(migrations))
+}
+
+func TestRepairSearchIndex(t *testing.T) {
+	if dao.DB.Dialector.Name() != "sqlite" {
+		t.Skip("only sqlite creates paste_search_fts")
+	}
+	assertNil(t, Up())
+	if !dao.DB.Migrator().HasTable("paste_search_fts") {
+		t.Skip("fts5 not available, build with -tags sqlite_fts5")
+	}
+
+	// 模拟在没有 FTS5 时执行过 v13 的数据库
+	for _, statement := range []string{
+		"DROP TRIGGER IF EXISTS paste_search_ai", "DROP TRIGGER IF EXISTS paste_search_ad",
+		"DROP TRIGGER IF EXISTS paste_search_au", "DROP TABLE IF EXISTS paste_search_fts",
+	} {
+		assertNil(t, dao.DB.Exec(statement).Error)
+	}
+	assertNil(t, dao.DB.Create(&pasteSearchV13{Key: "fts5repair", Username: "repairer", Lang: "plain", Content: "reindexed runbook"}).Error)
+	defer dao.DB.Delete(&pasteSearchV13{Key: "fts5repair"})
+
+	assertNil(t, Up())
+	var count int64
+	assertNil(t, dao.DB.Raw("SELECT COUNT(*) FROM paste_search_fts WHERE paste_search_fts MATCH ?", "runbook").Scan(&count).Error)
+	if count != 1 {
+		t.Fatalf("expect existing content reindexed, got %d matches", count)
+	}
+}
+
+func TestSizeBackfillCountsBytes(t *testing.T) {
+	assertNil(t, Up())
+	const content = "你好, world"
+	where := map[string]interface{}{"key": "sizebackfill"}
+	assertNil(t, dao.DB.Table("permanent").Create(map[string]interface{}{
+		"key": "sizebackfill", "lang": "plain", "content": content, "size": 0,
+	}).Error)
+	defer dao.DB.Table("permanent").Where(where).Delete(nil)
+
+	for _, m := range migrations {
+		if m.Version == 12 {
+			assertNil(t, dao.DB.Transaction(m.Up))
+		}
+	}
+	var size int64
+	assertNil(t, dao.DB.Table("permanent").Select("size").Where(where).Scan(&size).Error)
+	if size != int64(len(content)) {
+		t.Fatalf("expect %d bytes, got %d", len(content), size)
+	}
+}
@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+// 以下结构体是版本 1 时的表结构快照，之后的变更不要修改这里，而是新增 Migration
+
+type permanentV1 struct {
+	Key       string `gorm:"type:varchar(16);primaryKey"`
+	Lang      string `gorm:"type:varchar(16)"`
+	Content   string `gorm:"type:mediumtext"`
+	Password  string `gorm:"type:varchar(32)"`
+	ClientIP  string `gorm:"type:varchar(64)"`
+	Username  string `gorm:"type:varchar(16)"`
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (permanentV1) TableName() string {
+	return "permanent"
+}
+
+type temporaryV1 struct {
+	Key          string `gorm:"type:varchar(16);primaryKey"`
+	Lang         string `gorm:"type:varchar(16)"`
+	Content      string `gorm:"type:mediumtext"`
+	Password     string `gorm:"type:varchar(32)"`
+	ClientIP     string `gorm:"type:varchar(64)"`
+	Username     string `gorm:"type:varchar(16)"`
+	CreatedAt    time.Time
+	ExpireSecond uint64
+	ExpireCount  uint64
+}
+
+func (temporaryV1) TableName() string {
+	return "temporary"
+}
+
+type userV1 struct {
+	Username string `gorm:"type:varchar(32);primaryKey"`
+	Password string `gorm:"type:varchar(32)"`
+	Email    string `gorm:"type:varchar(128)"`
+}
+
+func (userV1) TableName() string {
+	return "user"
+}
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "create_tables",
+		Up: func(tx *gorm.DB) error {
+			// 已有部署在引入 migration 之前就建好了表，这里只创建缺失的表
+			for _, object := range []interface{}{&permanentV1{}, &temporaryV1{}, &userV1{}} {
+				if err := dao.CreateTable(tx, object); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&permanentV1{}, &temporaryV1{}, &userV1{})
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+// temporaryV2 只包含本次变更与补齐数据需要的字段
+type temporaryV2 struct {
+	Key          string `gorm:"type:varchar(16);primaryKey"`
+	CreatedAt    time.Time
+	ExpireSecond uint64
+	ExpiresAt    time.Time `gorm:"index"`
+}
+
+func (temporaryV2) TableName() string {
+	return "temporary"
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "temporary_expires_at",
+		Up: func(tx *gorm.DB) error {
+			if err := dao.AddColumn(tx, &temporaryV2{}, "ExpiresAt"); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasIndex(&temporaryV2{}, "ExpiresAt") {
+				if err := tx.Migrator().CreateIndex(&temporaryV2{}, "ExpiresAt"); err != nil {
+					return err
+				}
+			}
+
+			// 为新增字段之前创建的记录补齐过期时间
+			var pastes []temporaryV2
+			if err := tx.Where("expires_at IS NULL").Find(&pastes).Error; err != nil {
+				return err
+			}
+			for _, paste := range pastes {
+				expiresAt := paste.CreatedAt.Add(time.Second * time.Duration(paste.ExpireSecond))
+				if err := tx.Model(&temporaryV2{}).Where(map[string]interface{}{"key": paste.Key}).
+					Update("expires_at", expiresAt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return dao.DropColumn(tx, &temporaryV2{}, "ExpiresAt")
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+)
+
+type permanentV3 struct {
+	ContentStore string `gorm:"type:varchar(16)"`
+}
+
+func (permanentV3) TableName() string {
+	return "permanent"
+}
+
+type temporaryV3 struct {
+	ContentStore string `gorm:"type:varchar(16)"`
+}
+
+func (temporaryV3) TableName() string {
+	return "temporary"
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "content_store",
+		Up: func(tx *gorm.DB) error {
+			if err := dao.AddColumn(tx, &permanentV3{}, "ContentStore"); err != nil {
+				return err
+			}
+			return dao.AddColumn(tx, &temporaryV3{}, "ContentStore")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dao.DropColumn(tx, &permanentV3{}, "ContentStore"); err != nil {
+				return err
+			}
+			return dao.DropColumn(tx, &temporaryV3{}, "ContentStore")
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+)
+
+type permanentV4 struct {
+	Encoding          string `gorm:"type:varchar(16)"`
+	CompressedContent []byte `gorm:"size:16777215"`
+}
+
+func (permanentV4) TableName() string {
+	return "permanent"
+}
+
+type temporaryV4 struct {
+	Encoding          string `gorm:"type:varchar(16)"`
+	CompressedContent []byte `gorm:"size:16777215"`
+}
+
+func (temporaryV4) TableName() string {
+	return "temporary"
+}
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "compression",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV4{}, &temporaryV4{}} {
+				for _, field := range []string{"Encoding", "CompressedContent"} {
+					if err := dao.AddColumn(tx, object, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV4{}, &temporaryV4{}} {
+				for _, field := range []string{"Encoding", "CompressedContent"} {
+					if err := dao.DropColumn(tx, object, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+// 密码从 32 位的 MD5 改为 argon2id 的 PHC 字符串，需要加宽字段
+
+type permanentV5 struct {
+	Password string `gorm:"type:varchar(128)"`
+}
+
+func (permanentV5) TableName() string {
+	return "permanent"
+}
+
+type temporaryV5 struct {
+	Password string `gorm:"type:varchar(128)"`
+}
+
+func (temporaryV5) TableName() string {
+	return "temporary"
+}
+
+type userV5 struct {
+	Password string `gorm:"type:varchar(128)"`
+}
+
+func (userV5) TableName() string {
+	return "user"
+}
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "password_hash",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV5{}, &temporaryV5{}, &userV5{}} {
+				if err := tx.Migrator().AlterColumn(object, "Password"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// 新格式的哈希无法放回 varchar(32)，回滚前需要先清理这些记录
+			for _, object := range []interface{}{&permanentV1{}, &temporaryV1{}, &userV1{}} {
+				if err := tx.Migrator().AlterColumn(object, "Password"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+)
+
+type permanentV6 struct {
+	Encryption string `gorm:"type:varchar(16)"`
+}
+
+func (permanentV6) TableName() string {
+	return "permanent"
+}
+
+type temporaryV6 struct {
+	Encryption string `gorm:"type:varchar(16)"`
+}
+
+func (temporaryV6) TableName() string {
+	return "temporary"
+}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "encryption",
+		Up: func(tx *gorm.DB) error {
+			if err := dao.AddColumn(tx, &permanentV6{}, "Encryption"); err != nil {
+				return err
+			}
+			return dao.AddColumn(tx, &temporaryV6{}, "Encryption")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dao.DropColumn(tx, &permanentV6{}, "Encryption"); err != nil {
+				return err
+			}
+			return dao.DropColumn(tx, &temporaryV6{}, "Encryption")
+		},
+	})
+}
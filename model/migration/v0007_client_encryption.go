@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+)
+
+type permanentV7 struct {
+	Encrypted bool
+	IV        string `gorm:"type:varchar(64)"`
+	Salt      string `gorm:"type:varchar(64)"`
+}
+
+func (permanentV7) TableName() string {
+	return "permanent"
+}
+
+type temporaryV7 struct {
+	Encrypted bool
+	IV        string `gorm:"type:varchar(64)"`
+	Salt      string `gorm:"type:varchar(64)"`
+}
+
+func (temporaryV7) TableName() string {
+	return "temporary"
+}
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "client_encryption",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV7{}, &temporaryV7{}} {
+				for _, field := range []string{"Encrypted", "IV", "Salt"} {
+					if err := dao.AddColumn(tx, object, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV7{}, &temporaryV7{}} {
+				for _, field := range []string{"Encrypted", "IV", "Salt"} {
+					if err := dao.DropColumn(tx, object, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,65 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+type permanentV8 struct {
+	Revision  uint `gorm:"default:1"`
+	UpdatedAt time.Time
+}
+
+func (permanentV8) TableName() string {
+	return "permanent"
+}
+
+// pasteRevisionV8 保存 Permanent 被修改前的各个版本，与 Permanent 的内容字段一致
+type pasteRevisionV8 struct {
+	Key               string `gorm:"type:varchar(16);primaryKey"`
+	Revision          uint   `gorm:"primaryKey;autoIncrement:false"`
+	Lang              string `gorm:"type:varchar(16)"`
+	Content           string `gorm:"type:mediumtext"`
+	Password          string `gorm:"type:varchar(128)"`
+	ClientIP          string `gorm:"type:varchar(64)"`
+	Username          string `gorm:"type:varchar(16)"`
+	CreatedAt         time.Time
+	ContentStore      string `gorm:"type:varchar(16)"`
+	Encoding          string `gorm:"type:varchar(16)"`
+	Encryption        string `gorm:"type:varchar(16)"`
+	CompressedContent []byte `gorm:"size:16777215"`
+	Encrypted         bool
+	IV                string `gorm:"type:varchar(64)"`
+	Salt              string `gorm:"type:varchar(64)"`
+}
+
+func (pasteRevisionV8) TableName() string {
+	return "paste_revision"
+}
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "paste_revision",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"Revision", "UpdatedAt"} {
+				if err := dao.AddColumn(tx, &permanentV8{}, field); err != nil {
+					return err
+				}
+			}
+			return dao.CreateTable(tx, &pasteRevisionV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&pasteRevisionV8{}); err != nil {
+				return err
+			}
+			for _, field := range []string{"Revision", "UpdatedAt"} {
+				if err := dao.DropColumn(tx, &permanentV8{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+)
+
+type permanentV9 struct {
+	DeleteTokenHash string `gorm:"type:varchar(64)"`
+}
+
+func (permanentV9) TableName() string {
+	return "permanent"
+}
+
+type temporaryV9 struct {
+	DeleteTokenHash string `gorm:"type:varchar(64)"`
+}
+
+func (temporaryV9) TableName() string {
+	return "temporary"
+}
+
+type pasteRevisionV9 struct {
+	DeleteTokenHash string `gorm:"type:varchar(64)"`
+}
+
+func (pasteRevisionV9) TableName() string {
+	return "paste_revision"
+}
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "delete_token",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV9{}, &temporaryV9{}, &pasteRevisionV9{}} {
+				if err := dao.AddColumn(tx, object, "DeleteTokenHash"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV9{}, &temporaryV9{}, &pasteRevisionV9{}} {
+				if err := dao.DropColumn(tx, object, "DeleteTokenHash"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
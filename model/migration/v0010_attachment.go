@@ -0,0 +1,60 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+)
+
+type permanentV10 struct {
+	MimeType string `gorm:"type:varchar(128)"`
+	FileName string `gorm:"type:varchar(255)"`
+}
+
+func (permanentV10) TableName() string {
+	return "permanent"
+}
+
+type temporaryV10 struct {
+	MimeType string `gorm:"type:varchar(128)"`
+	FileName string `gorm:"type:varchar(255)"`
+}
+
+func (temporaryV10) TableName() string {
+	return "temporary"
+}
+
+type pasteRevisionV10 struct {
+	MimeType string `gorm:"type:varchar(128)"`
+	FileName string `gorm:"type:varchar(255)"`
+}
+
+func (pasteRevisionV10) TableName() string {
+	return "paste_revision"
+}
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "attachment",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV10{}, &temporaryV10{}, &pasteRevisionV10{}} {
+				for _, field := range []string{"MimeType", "FileName"} {
+					if err := dao.AddColumn(tx, object, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV10{}, &temporaryV10{}, &pasteRevisionV10{}} {
+				for _, field := range []string{"MimeType", "FileName"} {
+					if err := dao.DropColumn(tx, object, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+)
+
+type permanentV11 struct {
+	ParentKey string `gorm:"type:varchar(16);index"`
+}
+
+func (permanentV11) TableName() string {
+	return "permanent"
+}
+
+type temporaryV11 struct {
+	ParentKey string `gorm:"type:varchar(16);index"`
+}
+
+func (temporaryV11) TableName() string {
+	return "temporary"
+}
+
+type pasteRevisionV11 struct {
+	ParentKey string `gorm:"type:varchar(16)"`
+}
+
+func (pasteRevisionV11) TableName() string {
+	return "paste_revision"
+}
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "fork",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV11{}, &temporaryV11{}, &pasteRevisionV11{}} {
+				if err := dao.AddColumn(tx, object, "ParentKey"); err != nil {
+					return err
+				}
+			}
+			for _, object := range []interface{}{&permanentV11{}, &temporaryV11{}} {
+				if !tx.Migrator().HasIndex(object, "ParentKey") {
+					if err := tx.Migrator().CreateIndex(object, "ParentKey"); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV11{}, &temporaryV11{}} {
+				if tx.Migrator().HasIndex(object, "ParentKey") {
+					if err := tx.Migrator().DropIndex(object, "ParentKey"); err != nil {
+						return err
+					}
+				}
+			}
+			for _, object := range []interface{}{&permanentV11{}, &temporaryV11{}, &pasteRevisionV11{}} {
+				if err := dao.DropColumn(tx, object, "ParentKey"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
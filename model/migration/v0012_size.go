@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type permanentV12 struct {
+	Size int64
+}
+
+func (permanentV12) TableName() string {
+	return "permanent"
+}
+
+type temporaryV12 struct {
+	Size int64
+}
+
+func (temporaryV12) TableName() string {
+	return "temporary"
+}
+
+type pasteRevisionV12 struct {
+	Size int64
+}
+
+func (pasteRevisionV12) TableName() string {
+	return "paste_revision"
+}
+
+// byteLengthV12 Size 是字节数，SQLite 与 PostgreSQL 的 LENGTH 对文本返回的是字符数
+func byteLengthV12(tx *gorm.DB) clause.Expr {
+	switch tx.Dialector.Name() {
+	case "postgres":
+		return gorm.Expr("OCTET_LENGTH(content)")
+	case "sqlite":
+		return gorm.Expr("LENGTH(CAST(content AS BLOB))")
+	default:
+		return gorm.Expr("LENGTH(content)")
+	}
+}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "size",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV12{}, &temporaryV12{}, &pasteRevisionV12{}} {
+				if err := dao.AddColumn(tx, object, "Size"); err != nil {
+					return err
+				}
+				// 未压缩、未加密且存放在数据库中的旧记录可以直接得到大小，其余的旧记录大小为 0
+				if err := tx.Model(object).
+					Where("(encoding = '' OR encoding IS NULL) AND (encryption = '' OR encryption IS NULL)").
+					Where("(content_store = '' OR content_store IS NULL) AND (size = 0 OR size IS NULL)").
+					Update("size", byteLengthV12(tx)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&permanentV12{}, &temporaryV12{}, &pasteRevisionV12{}} {
+				if err := dao.DropColumn(tx, object, "Size"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// pasteSearchV13 全文索引的内容，只有允许搜索的 paste 会写入
+type pasteSearchV13 struct {
+	Key      string `gorm:"type:varchar(16);primaryKey"`
+	Username string `gorm:"type:varchar(16);index"`
+	Lang     string `gorm:"type:varchar(16)"`
+	Content  string `gorm:"type:mediumtext"`
+}
+
+func (pasteSearchV13) TableName() string {
+	return "paste_search"
+}
+
+// sqliteSearchV13 外部内容的 FTS5 表，由触发器与 paste_search 保持同步
+var sqliteSearchV13 = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS paste_search_fts USING fts5(content, content='paste_search', content_rowid='rowid')`,
+	`CREATE TRIGGER IF NOT EXISTS paste_search_ai AFTER INSERT ON paste_search BEGIN
+	INSERT INTO paste_search_fts(rowid, content) VALUES (new.rowid, new.content);
+END`,
+	`CREATE TRIGGER IF NOT EXISTS paste_search_ad AFTER DELETE ON paste_search BEGIN
+	INSERT INTO paste_search_fts(paste_search_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+END`,
+	`CREATE TRIGGER IF NOT EXISTS paste_search_au AFTER UPDATE ON paste_search BEGIN
+	INSERT INTO paste_search_fts(paste_search_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+	INSERT INTO paste_search_fts(rowid, content) VALUES (new.rowid, new.content);
+END`,
+}
+
+// sqliteSearchUpV13 创建 FTS5 表与触发器，并从 paste_search 重建索引
+// FTS5 需要以 sqlite_fts5 编译，不可用时搜索退化为 LIKE
+func sqliteSearchUpV13(tx *gorm.DB) error {
+	if err := tx.Exec(sqliteSearchV13[0]).Error; err != nil {
+		logging.Warn("fts5 not available, search falls back to like", zap.Error(err))
+		return nil
+	}
+	for _, statement := range sqliteSearchV13[1:] {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Exec("INSERT INTO paste_search_fts(paste_search_fts) VALUES ('rebuild')").Error
+}
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "search",
+		Up: func(tx *gorm.DB) error {
+			if err := dao.CreateTable(tx, &pasteSearchV13{}); err != nil {
+				return err
+			}
+			switch tx.Dialector.Name() {
+			case "mysql":
+				if tx.Migrator().HasIndex(&pasteSearchV13{}, "idx_paste_search_content") {
+					return nil
+				}
+				return tx.Exec("CREATE FULLTEXT INDEX idx_paste_search_content ON paste_search (content)").Error
+			case "postgres":
+				return tx.Exec("CREATE INDEX IF NOT EXISTS idx_paste_search_content ON paste_search " +
+					"USING GIN (to_tsvector('simple', content))").Error
+			case "sqlite":
+				return sqliteSearchUpV13(tx)
+			}
+			return nil
+		},
+		// 没有 FTS5 时执行的 v13 不会创建 paste_search_fts，之后以 sqlite_fts5 编译启动时补上
+		Repair: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "sqlite" || tx.Migrator().HasTable("paste_search_fts") {
+				return nil
+			}
+			return sqliteSearchUpV13(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "sqlite" {
+				for _, statement := range []string{
+					"DROP TRIGGER IF EXISTS paste_search_ai", "DROP TRIGGER IF EXISTS paste_search_ad",
+					"DROP TRIGGER IF EXISTS paste_search_au", "DROP TABLE IF EXISTS paste_search_fts",
+				} {
+					if err := tx.Exec(statement).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return tx.Migrator().DropTable(&pasteSearchV13{})
+		},
+	})
+}
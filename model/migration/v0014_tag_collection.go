@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+// pasteTagV14 用户给自己的 paste 打上的标签
+type pasteTagV14 struct {
+	Key      string `gorm:"type:varchar(16);primaryKey"`
+	Name     string `gorm:"type:varchar(32);primaryKey"`
+	Username string `gorm:"type:varchar(16);index"`
+}
+
+func (pasteTagV14) TableName() string {
+	return "paste_tag"
+}
+
+type collectionV14 struct {
+	ID        string `gorm:"type:varchar(16);primaryKey"`
+	Username  string `gorm:"type:varchar(16);index"`
+	Name      string `gorm:"type:varchar(64)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (collectionV14) TableName() string {
+	return "collection"
+}
+
+// collectionItemV14 合集中的一贴，Position 决定顺序
+type collectionItemV14 struct {
+	CollectionID string `gorm:"type:varchar(16);primaryKey"`
+	Key          string `gorm:"type:varchar(16);primaryKey;index"`
+	Position     int
+}
+
+func (collectionItemV14) TableName() string {
+	return "collection_item"
+}
+
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "tag_collection",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&pasteTagV14{}, &collectionV14{}, &collectionItemV14{}} {
+				if err := dao.CreateTable(tx, object); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&collectionItemV14{}, &collectionV14{}, &pasteTagV14{})
+		},
+	})
+}
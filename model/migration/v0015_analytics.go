@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+// pasteViewV15 一贴每小时的查看次数
+type pasteViewV15 struct {
+	Key   string    `gorm:"type:varchar(16);primaryKey"`
+	Hour  time.Time `gorm:"primaryKey;autoCreateTime:false"`
+	Views uint64
+}
+
+func (pasteViewV15) TableName() string {
+	return "paste_view"
+}
+
+// pasteViewSummaryV15 一贴的总查看次数与估算独立查看者的 HyperLogLog
+type pasteViewSummaryV15 struct {
+	Key       string `gorm:"type:varchar(16);primaryKey"`
+	Views     uint64
+	Sketch    []byte `gorm:"size:1024"`
+	UpdatedAt time.Time
+}
+
+func (pasteViewSummaryV15) TableName() string {
+	return "paste_view_summary"
+}
+
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "analytics",
+		Up: func(tx *gorm.DB) error {
+			for _, object := range []interface{}{&pasteViewV15{}, &pasteViewSummaryV15{}} {
+				if err := dao.CreateTable(tx, object); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&pasteViewSummaryV15{}, &pasteViewV15{})
+		},
+	})
+}
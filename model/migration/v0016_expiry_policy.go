@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+// temporaryV16 绝对过期时间与闲置过期
+type temporaryV16 struct {
+	ExpireAt   *time.Time
+	IdleSecond uint64
+}
+
+func (temporaryV16) TableName() string {
+	return "temporary"
+}
+
+// permanentV16 创建者设置的删除时间
+type permanentV16 struct {
+	DeleteAt *time.Time `gorm:"index"`
+}
+
+func (permanentV16) TableName() string {
+	return "permanent"
+}
+
+func init() {
+	register(Migration{
+		Version: 16,
+		Name:    "expiry_policy",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"ExpireAt", "IdleSecond"} {
+				if err := dao.AddColumn(tx, &temporaryV16{}, field); err != nil {
+					return err
+				}
+			}
+			if err := dao.AddColumn(tx, &permanentV16{}, "DeleteAt"); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasIndex(&permanentV16{}, "DeleteAt") {
+				return tx.Migrator().CreateIndex(&permanentV16{}, "DeleteAt")
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasIndex(&permanentV16{}, "DeleteAt") {
+				if err := tx.Migrator().DropIndex(&permanentV16{}, "DeleteAt"); err != nil {
+					return err
+				}
+			}
+			if err := dao.DropColumn(tx, &permanentV16{}, "DeleteAt"); err != nil {
+				return err
+			}
+			for _, field := range []string{"IdleSecond", "ExpireAt"} {
+				if err := dao.DropColumn(tx, &temporaryV16{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
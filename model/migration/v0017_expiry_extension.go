@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+// expiryExtensionV17 创建者每一次延长过期时间或转为永久的记录
+type expiryExtensionV17 struct {
+	ID          uint   `gorm:"primaryKey"`
+	Key         string `gorm:"type:varchar(16);index"`
+	Username    string `gorm:"type:varchar(16)"`
+	ExpiresAt   *time.Time
+	ExpireCount uint64
+	IdleSecond  uint64
+	Permanent   bool
+	CreatedAt   time.Time
+}
+
+func (expiryExtensionV17) TableName() string {
+	return "expiry_extension"
+}
+
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "expiry_extension",
+		Up: func(tx *gorm.DB) error {
+			return dao.CreateTable(tx, &expiryExtensionV17{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&expiryExtensionV17{})
+		},
+	})
+}
@@ -0,0 +1,308 @@
+package paste
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	sketchPrecision = 10 // HyperLogLog 使用 2^10 个寄存器，标准误差约为 3%
+	sketchSize      = 1 << sketchPrecision
+)
+
+// PasteView 一贴在一个小时内的查看次数
+type PasteView struct {
+	Key   string    `gorm:"type:varchar(16);primaryKey"`
+	Hour  time.Time `gorm:"primaryKey;autoCreateTime:false"` // 小时的开始，UTC
+	Views uint64
+}
+
+// PasteViewSummary 一贴的总查看次数与查看者的 HyperLogLog，只保存哈希后的寄存器，无法还原出 IP
+type PasteViewSummary struct {
+	Key       string `gorm:"type:varchar(16);primaryKey"`
+	Views     uint64
+	Sketch    []byte `gorm:"size:1024"`
+	UpdatedAt time.Time
+}
+
+// ViewBucket 一个时间段内的查看次数
+type ViewBucket struct {
+	Start time.Time `json:"start"`
+	Views uint64    `json:"views" example:"12"`
+}
+
+// ViewStats 一贴的查看统计，只包含汇总后的数据
+type ViewStats struct {
+	Views          uint64       `json:"views" example:"120"`
+	UniqueViewers  uint64       `json:"unique_viewers" example:"37"` // 估算值
+	Buckets        []ViewBucket `json:"buckets"`
+	BucketInterval string       `json:"bucket_interval" example:"hour"`
+}
+
+// sketch HyperLogLog 的寄存器
+type sketch []byte
+
+func newSketch() sketch {
+	return make(sketch, sketchSize)
+}
+
+func (s sketch) add(viewer string) {
+	sum := sha256.Sum256([]byte(viewer))
+	hash := binary.BigEndian.Uint64(sum[:8])
+	index := hash >> (64 - sketchPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<sketchPrecision|1<<(sketchPrecision-1)) + 1)
+	if rank > s[index] {
+		s[index] = rank
+	}
+}
+
+func (s sketch) merge(other sketch) {
+	for i := range s {
+		if i < len(other) && other[i] > s[i] {
+			s[i] = other[i]
+		}
+	}
+}
+
+func (s sketch) estimate() uint64 {
+	sum, zeros := 0.0, 0
+	for _, rank := range s {
+		sum += math.Pow(2, -float64(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	m := float64(sketchSize)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros)) // 基数较小时使用线性计数
+	}
+	return uint64(math.Round(estimate))
+}
+
+// pendingViews 尚未写入数据库的查看记录
+type pendingViews struct {
+	hours  map[time.Time]uint64
+	sketch sketch
+}
+
+var (
+	viewsMutex sync.Mutex
+	views      = make(map[string]*pendingViews)
+	viewsFull  = make(chan struct{}, 1) // 缓冲的 paste 数量达到 batchSize 时通知写入
+	batchSize  = 1000
+)
+
+// RecordView 记录一次查看，只写入内存，由 StartAnalytics 启动的协程分批写入数据库
+// viewer 为查看者的标识，只用于估算独立查看者的数量，不会保存
+func RecordView(key string, viewer string) {
+	viewsMutex.Lock()
+	defer viewsMutex.Unlock()
+	pending, ok := views[key]
+	if !ok {
+		pending = &pendingViews{hours: make(map[time.Time]uint64), sketch: newSketch()}
+		views[key] = pending
+	}
+	pending.hours[time.Now().UTC().Truncate(time.Hour)]++
+	pending.sketch.add(viewer)
+	if len(views) >= batchSize {
+		select {
+		case viewsFull <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// requeue 写入失败时放回缓冲，等待下一次写入
+func requeue(key string, failed *pendingViews) {
+	viewsMutex.Lock()
+	defer viewsMutex.Unlock()
+	pending, ok := views[key]
+	if !ok {
+		views[key] = failed
+		return
+	}
+	for hour, count := range failed.hours {
+		pending.hours[hour] += count
+	}
+	pending.sketch.merge(failed.sketch)
+}
+
+// flushViews 把缓冲的查看记录写入数据库，返回写入的 paste 数量
+func flushViews() (int, error) {
+	viewsMutex.Lock()
+	flushing := views
+	views = make(map[string]*pendingViews)
+	viewsMutex.Unlock()
+
+	var lastErr error
+	for key, pending := range flushing {
+		if err := dao.DB.Transaction(func(tx *gorm.DB) error {
+			return pending.save(tx, key)
+		}); err != nil {
+			requeue(key, pending)
+			lastErr = err
+		}
+	}
+	return len(flushing), lastErr
+}
+
+func (pending *pendingViews) save(tx *gorm.DB, key string) error {
+	count := int64(0)
+	if err := tx.Unscoped().Model(&Permanent{}).Where(map[string]interface{}{"key": key}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil // 已经彻底删除
+	}
+
+	total := uint64(0)
+	for hour, views := range pending.hours {
+		total += views
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}, {Name: "hour"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"views": gorm.Expr("paste_view.views + ?", views)}),
+		}).Create(&PasteView{Key: key, Hour: hour, Views: views}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&PasteViewSummary{Key: key, Sketch: newSketch()}).Error; err != nil {
+		return err
+	}
+	summary := PasteViewSummary{Key: key}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&summary).Error; err != nil {
+		return err
+	}
+	merged := newSketch()
+	merged.merge(summary.Sketch)
+	merged.merge(pending.sketch)
+	return tx.Model(&summary).Updates(map[string]interface{}{
+		"views": summary.Views + total, "sketch": []byte(merged), "updated_at": time.Now(),
+	}).Error
+}
+
+// Stats 成员函数，返回查看统计，只有创建者和管理员可以查看
+// daily 为 true 时按天汇总最近 30 天，否则按小时汇总最近 48 小时，尚未写入数据库的查看不计入
+func (paste *Permanent) Stats(username string, admin bool, daily bool) (*ViewStats, error) {
+	if err := dao.DB.Select("key", "username").Take(&paste).Error; err != nil {
+		return nil, err
+	}
+	if !admin && (paste.Username == "" || paste.Username != username) {
+		return nil, common.ErrNotOwner
+	}
+
+	stats := &ViewStats{Buckets: make([]ViewBucket, 0), BucketInterval: "hour"}
+	var summary PasteViewSummary
+	if err := dao.DB.Where(map[string]interface{}{"key": paste.Key}).Limit(1).Find(&summary).Error; err != nil {
+		return nil, err
+	}
+	stats.Views = summary.Views
+	if len(summary.Sketch) > 0 {
+		stats.UniqueViewers = sketch(summary.Sketch).estimate()
+	}
+
+	width, since := time.Hour, time.Now().UTC().Truncate(time.Hour).Add(-47*time.Hour)
+	if daily {
+		stats.BucketInterval = "day"
+		width, since = 24*time.Hour, time.Now().UTC().Truncate(24*time.Hour).Add(-29*24*time.Hour)
+	}
+	var rows []PasteView
+	if err := dao.DB.Where(map[string]interface{}{"key": paste.Key}).Where("hour >= ?", since).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	buckets := make(map[time.Time]uint64)
+	for _, row := range rows {
+		buckets[row.Hour.UTC().Truncate(width)] += row.Views
+	}
+	for start, views := range buckets {
+		stats.Buckets = append(stats.Buckets, ViewBucket{Start: start, Views: views})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool {
+		return stats.Buckets[i].Start.Before(stats.Buckets[j].Start)
+	})
+	return stats, nil
+}
+
+// analytics 后台定期写入缓冲的查看记录
+type analytics struct {
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var (
+	runningAnalytics *analytics
+	analyticsMutex   sync.Mutex
+)
+
+// StartAnalytics 启动后台写入协程，缓冲的 paste 数量达到 size 时提前写入，重复调用时不会启动多个
+func StartAnalytics(interval time.Duration, size int) {
+	analyticsMutex.Lock()
+	defer analyticsMutex.Unlock()
+
+	if runningAnalytics != nil {
+		return
+	}
+	if size > 0 {
+		viewsMutex.Lock()
+		batchSize = size
+		viewsMutex.Unlock()
+	}
+	runningAnalytics = &analytics{interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+	go runningAnalytics.run()
+	logging.Info("analytics started", zap.Duration("interval", interval), zap.Int("batch_size", size))
+}
+
+// StopAnalytics 停止后台写入协程，并写入剩余的查看记录
+func StopAnalytics() {
+	analyticsMutex.Lock()
+	defer analyticsMutex.Unlock()
+
+	if runningAnalytics == nil {
+		return
+	}
+	close(runningAnalytics.stop)
+	<-runningAnalytics.done
+	runningAnalytics = nil
+	logging.Info("analytics stopped")
+}
+
+func (a *analytics) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			a.flush()
+			return
+		case <-ticker.C:
+			a.flush()
+		case <-viewsFull:
+			a.flush()
+		}
+	}
+}
+
+func (a *analytics) flush() {
+	count, err := flushViews()
+	if err != nil {
+		logging.Error("flush views failed", zap.Int("count", count), zap.Error(err))
+	}
+}
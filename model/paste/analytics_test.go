@@ -0,0 +1,64 @@
+package paste
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"math"
+	"testing"
+)
+
+func TestSketchEstimate(t *testing.T) {
+	for _, n := range []int{10, 1000, 20000} {
+		s := newSketch()
+		for i := 0; i < n; i++ {
+			s.add(fmt.Sprintf("203.0.113.%d", i))
+			s.add(fmt.Sprintf("203.0.113.%d", i)) // 重复的查看者只计一次
+		}
+		if relative := math.Abs(float64(s.estimate())-float64(n)) / float64(n); relative > 0.1 {
+			t.Errorf("estimate %d for %d distinct viewers", s.estimate(), n)
+		}
+	}
+}
+
+func TestViewStats(t *testing.T) {
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "popular", Username: "viewer-owner"}}
+	assertNil(t, paste.Save())
+
+	for i := 0; i < 5; i++ {
+		RecordView(paste.Key, "198.51.100.1")
+	}
+	RecordView(paste.Key, "198.51.100.2")
+	count, err := flushViews()
+	assertNil(t, err)
+	assertEqual(t, 1, count)
+	RecordView(paste.Key, "198.51.100.1")
+	_, err = flushViews()
+	assertNil(t, err)
+
+	if _, err = (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Stats("someone", false, false); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	stats, err := (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Stats("viewer-owner", false, false)
+	assertNil(t, err)
+	assertEqual(t, uint64(7), stats.Views)
+	assertEqual(t, uint64(2), stats.UniqueViewers)
+	assertEqual(t, 1, len(stats.Buckets))
+	assertEqual(t, uint64(7), stats.Buckets[0].Views)
+
+	stats, err = (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Stats("someone", true, true)
+	assertNil(t, err)
+	assertEqual(t, "day", stats.BucketInterval)
+	assertEqual(t, uint64(7), stats.Buckets[0].Views)
+
+	// 彻底删除后统计一并删除，之后的查看不再写入
+	_, err = destroy([]string{paste.Key})
+	assertNil(t, err)
+	RecordView(paste.Key, "198.51.100.1")
+	_, err = flushViews()
+	assertNil(t, err)
+	count64 := int64(0)
+	assertNil(t, dao.DB.Model(&PasteView{}).Where(map[string]interface{}{"key": paste.Key}).Count(&count64).Error)
+	assertEqual(t, int64(0), count64)
+}
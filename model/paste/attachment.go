@@ -0,0 +1,55 @@
+package paste
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// BinaryLang 二进制附件的语言类型，实际类型保存在 MimeType 中
+const BinaryLang = "binary"
+
+// SetData 设置二进制附件的内容与原始文件名，MIME 类型根据内容检测，不信任客户端声明的类型
+func (paste *AbstractPaste) SetData(data []byte, fileName string) {
+	paste.Data, paste.FileName = data, fileName
+	paste.MimeType = strings.SplitN(http.DetectContentType(data), ";", 2)[0]
+}
+
+// IsBinary 是否为二进制附件
+func (paste *AbstractPaste) IsBinary() bool {
+	return paste.MimeType != ""
+}
+
+func (paste *AbstractPaste) GetData() []byte {
+	return paste.Data
+}
+
+func (paste *AbstractPaste) GetMimeType() string {
+	return paste.MimeType
+}
+
+func (paste *AbstractPaste) GetFileName() string {
+	return paste.FileName
+}
+
+// encodeData 二进制内容以 base64 存放到 Content 中，之后与文本一样压缩、加密和存储
+// 压缩后 base64 带来的膨胀基本可以抵消，且所有数据库都能安全保存
+func (paste *AbstractPaste) encodeData() {
+	if !paste.IsBinary() {
+		return
+	}
+	paste.Lang, paste.Content = BinaryLang, base64.StdEncoding.EncodeToString(paste.Data)
+}
+
+// decodeData 读取后把 Content 中的 base64 还原为二进制内容
+func (paste *AbstractPaste) decodeData() error {
+	if !paste.IsBinary() {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(paste.Content)
+	if err != nil {
+		return err
+	}
+	paste.Data, paste.Content = data, ""
+	return nil
+}
@@ -0,0 +1,41 @@
+package paste
+
+import (
+	"bytes"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89")
+
+func TestBinary(t *testing.T) {
+	data := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0, 0xff, 0xfe}, 1024)...)
+
+	paste := Permanent{AbstractPaste: &AbstractPaste{Password: "secret"}}
+	paste.SetData(data, "screenshot.png")
+	assertEqual(t, "image/png", paste.GetMimeType())
+	assertNil(t, paste.Save())
+	assertEqual(t, BinaryLang, paste.Lang)
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, AcceptEncoding: "gzip"}}
+	assertNil(t, got.Get("secret"))
+	assertEqual(t, true, got.IsBinary())
+	assertEqual(t, true, bytes.Equal(data, got.GetData()))
+	assertEqual(t, "screenshot.png", got.GetFileName())
+	assertEqual(t, "image/png", got.GetMimeType())
+}
+
+func TestBinaryTemporary(t *testing.T) {
+	data := []byte{0x7f, 'E', 'L', 'F', 0, 1, 2, 3}
+
+	paste := Temporary{AbstractPaste: &AbstractPaste{}, ExpireSecond: 60, ExpireCount: 1}
+	paste.SetData(data, "core")
+	assertEqual(t, "application/octet-stream", paste.GetMimeType())
+	assertNil(t, paste.Save())
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, true, bytes.Equal(data, got.GetData()))
+
+	again := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertEqual(t, true, again.Get("") != nil) // 与文本一样只能查看一次
+}
@@ -0,0 +1,56 @@
+package paste
+
+import (
+	"encoding/json"
+)
+
+// BundleLang 多文件 paste 的语言类型，各文件的语言类型保存在 File 中
+const BundleLang = "bundle"
+
+// File 多文件 paste 中的一个文件
+type File struct {
+	Name    string `json:"name" example:"Dockerfile"`
+	Lang    string `json:"lang" example:"plain"`
+	Content string `json:"content" example:"FROM golang:1.22"`
+}
+
+// IsBundle 是否为多文件 paste
+func (paste *AbstractPaste) IsBundle() bool {
+	return paste.Lang == BundleLang
+}
+
+// GetFiles 返回多文件 paste 中的全部文件，单文件时为空
+func (paste *AbstractPaste) GetFiles() []File {
+	return paste.Files
+}
+
+// GetFile 按文件名查找文件
+func (paste *AbstractPaste) GetFile(name string) (File, bool) {
+	for _, file := range paste.Files {
+		if file.Name == name {
+			return file, true
+		}
+	}
+	return File{}, false
+}
+
+// bundle 多文件时把全部文件编码为 JSON 存放到 Content 中，之后与单文件一样压缩、加密和存储
+func (paste *AbstractPaste) bundle() error {
+	if len(paste.Files) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(paste.Files)
+	if err != nil {
+		return err
+	}
+	paste.Lang, paste.Content = BundleLang, string(data)
+	return nil
+}
+
+// unbundle 读取后把 Content 中的 JSON 解析为文件列表
+func (paste *AbstractPaste) unbundle() error {
+	if !paste.IsBundle() {
+		return nil
+	}
+	return json.Unmarshal([]byte(paste.Content), &paste.Files)
+}
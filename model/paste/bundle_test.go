@@ -0,0 +1,42 @@
+package paste
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	files := []File{
+		{Name: "Dockerfile", Lang: "bash", Content: "FROM golang:1.22"},
+		{Name: "config.json", Lang: "json", Content: strings.Repeat(`{"debug": true}`, 100)},
+	}
+	paste := Permanent{AbstractPaste: &AbstractPaste{Files: files, Password: "secret"}}
+	assertNil(t, paste.Save())
+	assertEqual(t, BundleLang, paste.Lang)
+
+	// 调用方可以接收 gzip 时多文件仍然需要解压后才能拆分
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, AcceptEncoding: "gzip"}}
+	assertNil(t, got.Get("secret"))
+	assertEqual(t, true, got.IsBundle())
+	assertEqual(t, 2, len(got.GetFiles()))
+	assertEqual(t, "", got.GetEncoding())
+
+	file, ok := got.GetFile("config.json")
+	assertEqual(t, true, ok)
+	assertEqual(t, files[1].Content, file.Content)
+	assertEqual(t, "json", file.Lang)
+
+	_, ok = got.GetFile("missing.txt")
+	assertEqual(t, false, ok)
+}
+
+func TestBundleTemporary(t *testing.T) {
+	paste := Temporary{AbstractPaste: &AbstractPaste{Files: []File{{Name: "a.log", Lang: "plain", Content: "log"}}}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, paste.Save())
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	file, ok := got.GetFile("a.log")
+	assertEqual(t, true, ok)
+	assertEqual(t, "log", file.Content)
+}
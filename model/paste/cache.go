@@ -0,0 +1,119 @@
+package paste
+
+import (
+	"container/list"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"sync"
+)
+
+// cacheEntryOverhead 估算每一项除内容以外占用的字节数
+const cacheEntryOverhead = 512
+
+// CacheStats 读缓存的统计
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Size      int64  `json:"size"`     // 当前占用的字节数
+	Capacity  int64  `json:"capacity"` // 最大占用的字节数，为 0 时不缓存
+}
+
+type cacheEntry struct {
+	key   string
+	paste Permanent
+	size  int64
+}
+
+// cache 按字节数限制大小的 LRU 缓存，保存从数据库和外部存储中读出、尚未解密的永久 paste
+// 解密与密码校验在每次读取时都会执行，缓存中只有数据库中保存的密码哈希
+type cache struct {
+	mutex    sync.Mutex
+	capacity int64
+	size     int64
+	epoch    uint64 // 每次失效加一，读取开始后发生过失效的结果不会放入缓存
+	items    map[string]*list.Element
+	order    *list.List // 最近使用的在前
+	stats    CacheStats
+}
+
+var readCache = newCache(config.Config.Cache.MaxSize)
+
+func newCache(capacity int64) *cache {
+	return &cache{capacity: capacity, items: make(map[string]*list.Element), order: list.New()}
+}
+
+// clone 复制一份，调用方修改字段不会影响缓存，内容的字节切片只读，可以共用
+func (paste *Permanent) clone() Permanent {
+	copied := *paste
+	abstract := *paste.AbstractPaste
+	copied.AbstractPaste = &abstract
+	return copied
+}
+
+// get 命中时返回复制的 paste，未命中时返回放入缓存需要的 epoch
+func (c *cache) get(key string) (*Permanent, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.items[key]; ok {
+		c.order.MoveToFront(element)
+		c.stats.Hits++
+		paste := element.Value.(*cacheEntry).paste.clone()
+		return &paste, c.epoch
+	}
+	if c.capacity > 0 {
+		c.stats.Misses++
+	}
+	return nil, c.epoch
+}
+
+// put 放入缓存，epoch 之后发生过失效时放弃，避免放入已经被修改或删除的旧内容
+// 超过容量八分之一的 paste 不会缓存，以免一项挤掉其余全部
+func (c *cache) put(paste *Permanent, epoch uint64) {
+	size := int64(len(paste.Content)+len(paste.EncodedContent)+len(paste.Password)) + cacheEntryOverhead
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.capacity <= 0 || size > c.capacity/8 || epoch != c.epoch {
+		return
+	}
+	if element, ok := c.items[paste.Key]; ok {
+		c.remove(element)
+	}
+	c.items[paste.Key] = c.order.PushFront(&cacheEntry{key: paste.Key, paste: paste.clone(), size: size})
+	c.size += size
+	for c.size > c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// invalidate 在修改或删除后调用
+func (c *cache) invalidate(keys ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.epoch++
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.remove(element)
+		}
+	}
+}
+
+func (c *cache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.size -= entry.size
+}
+
+func (c *cache) statistics() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.stats
+	stats.Entries, stats.Size, stats.Capacity = len(c.items), c.size, c.capacity
+	return stats
+}
+
+// ReadCacheStats 返回永久 paste 读缓存的命中统计
+func ReadCacheStats() CacheStats {
+	return readCache.statistics()
+}
@@ -0,0 +1,101 @@
+package paste
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func useCache(t *testing.T, capacity int64) *cache {
+	previous := readCache
+	readCache = newCache(capacity)
+	t.Cleanup(func() {
+		readCache = previous
+	})
+	return readCache
+}
+
+func getPermanent(key string, password string) (*Permanent, error) {
+	paste := &Permanent{AbstractPaste: &AbstractPaste{Key: key}}
+	return paste, paste.Get(password)
+}
+
+func TestReadCache(t *testing.T) {
+	c := useCache(t, 1024*1024)
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "cached content", Password: "secret", Username: "cacher"}}
+	assertNil(t, paste.Save())
+
+	for i := 0; i < 2; i++ {
+		got, err := getPermanent(paste.Key, "secret")
+		assertNil(t, err)
+		assertEqual(t, "cached content", got.Content)
+	}
+	// 命中缓存时仍然校验密码
+	if _, err := getPermanent(paste.Key, "wrong"); !errors.Is(err, common.ErrWrongPassword) {
+		t.Fatalf("expected wrong password, got %v", err)
+	}
+	stats := c.statistics()
+	assertEqual(t, uint64(2), stats.Hits)
+	assertEqual(t, uint64(1), stats.Misses)
+	assertEqual(t, 1, stats.Entries)
+
+	// 命中时不访问数据库
+	assertNil(t, dao.DB.Model(&Permanent{}).Where(map[string]interface{}{"key": paste.Key}).Update("lang", "go").Error)
+	got, err := getPermanent(paste.Key, "secret")
+	assertNil(t, err)
+	assertEqual(t, "plain", got.Lang)
+
+	edit := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, Lang: "plain", Content: "edited content", Password: "secret"}}
+	assertNil(t, edit.Edit("cacher", 1))
+	got, err = getPermanent(paste.Key, "secret")
+	assertNil(t, err)
+	assertEqual(t, "edited content", got.Content)
+
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Remove("cacher", false))
+	if _, err = getPermanent(paste.Key, "secret"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+}
+
+func TestReadCacheSkipsTemporary(t *testing.T) {
+	c := useCache(t, 1024*1024)
+	paste := Temporary{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "temporary"}, ExpireSecond: 60, ExpireCount: 2}
+	assertNil(t, paste.Save())
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, CacheStats{Capacity: 1024 * 1024}, c.statistics())
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache(8 * 1024)
+	for i := 0; i < 32; i++ {
+		c.put(&Permanent{AbstractPaste: &AbstractPaste{Key: fmt.Sprintf("key%d", i), Content: "content"}}, 0)
+	}
+	stats := c.statistics()
+	assertEqual(t, true, stats.Size <= stats.Capacity)
+	assertEqual(t, uint64(32-stats.Entries), stats.Evictions)
+	if cached, _ := c.get("key31"); cached == nil {
+		t.Fatal("expected the most recent entry to be cached")
+	}
+	if cached, _ := c.get("key0"); cached != nil {
+		t.Fatal("expected the oldest entry to be evicted")
+	}
+
+	// 读取开始后发生过失效的结果不会放入缓存
+	_, epoch := c.get("stale")
+	c.invalidate("other")
+	c.put(&Permanent{AbstractPaste: &AbstractPaste{Key: "stale", Content: "old"}}, epoch)
+	if cached, _ := c.get("stale"); cached != nil {
+		t.Fatal("expected stale entry to be discarded")
+	}
+
+	// 超过容量八分之一的不缓存
+	_, epoch = c.get("large")
+	c.put(&Permanent{AbstractPaste: &AbstractPaste{Key: "large", Content: string(make([]byte, 2048))}}, epoch)
+	if cached, _ := c.get("large"); cached != nil {
+		t.Fatal("expected large entry to be skipped")
+	}
+}
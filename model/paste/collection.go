@@ -0,0 +1,180 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+const (
+	MaxCollectionItems      = 100
+	maxCollectionNameLength = 64
+)
+
+// Collection 合集，按顺序排列的一组永久 paste，通过 ID 分享
+type Collection struct {
+	ID        string      `json:"id" example:"k3j8x0qa" gorm:"type:varchar(16);primaryKey"`
+	Username  string      `json:"username" example:"alice" gorm:"type:varchar(16);index"`
+	Name      string      `json:"name" example:"incident-42" gorm:"type:varchar(64)"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Keys      []string    `json:"-" gorm:"-"`      // 保存时的成员，按顺序排列
+	Pastes    []PasteInfo `json:"pastes" gorm:"-"` // 读取时成员的元信息，回收站中的成员不会出现
+}
+
+// CollectionItem 合集中的一贴，Position 决定顺序
+type CollectionItem struct {
+	CollectionID string `gorm:"type:varchar(16);primaryKey"`
+	Key          string `gorm:"type:varchar(16);primaryKey;index"`
+	Position     int
+}
+
+// CollectionInfo 合集列表中的一项，不包含成员
+type CollectionInfo struct {
+	ID        string    `json:"id" example:"k3j8x0qa"`
+	Name      string    `json:"name" example:"incident-42"`
+	Size      int64     `json:"size" example:"3"` // 成员数量
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// normalize 校验名称，去除重复的成员
+func (collection *Collection) normalize() error {
+	collection.Name = strings.TrimSpace(collection.Name)
+	if collection.Name == "" || len([]rune(collection.Name)) > maxCollectionNameLength {
+		return common.ErrInvalidCollectionName
+	}
+	keys := make([]string, 0, len(collection.Keys))
+	for _, key := range collection.Keys {
+		if key = NormalizeKey(key); !contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > MaxCollectionItems {
+		return common.ErrTooManyCollectionItems
+	}
+	collection.Keys = keys
+	return nil
+}
+
+// saveItems 在事务中替换合集的全部成员，成员必须是未删除的永久 paste
+func (collection *Collection) saveItems(tx *gorm.DB) error {
+	if len(collection.Keys) > 0 {
+		count := int64(0)
+		if err := tx.Model(&Permanent{}).Where(map[string]interface{}{"key": collection.Keys}).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(collection.Keys)) {
+			return common.ErrInvalidCollectionItem
+		}
+	}
+	if err := tx.Where(map[string]interface{}{"collection_id": collection.ID}).Delete(&CollectionItem{}).Error; err != nil {
+		return err
+	}
+	if len(collection.Keys) == 0 {
+		return nil
+	}
+	items := make([]CollectionItem, 0, len(collection.Keys))
+	for position, key := range collection.Keys {
+		items = append(items, CollectionItem{CollectionID: collection.ID, Key: key, Position: position})
+	}
+	return tx.Create(&items).Error
+}
+
+// Save 成员函数，创建合集，ID 冲突时重新生成
+func (collection *Collection) Save() error {
+	if err := collection.normalize(); err != nil {
+		return err
+	}
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		collection.ID = generator(false)
+		err := dao.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&collection).Error; err != nil {
+				return err
+			}
+			return collection.saveItems(tx)
+		})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		logging.Warn("collection id collided", zap.String("id", collection.ID), zap.Int("attempt", attempt))
+	}
+	return common.ErrKeyCollision
+}
+
+// Update 成员函数，替换合集的名称与全部成员，只有创建者可以修改
+func (collection *Collection) Update(username string) error {
+	if err := collection.normalize(); err != nil {
+		return err
+	}
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		current := Collection{ID: collection.ID}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&current).Error; err != nil {
+			return err
+		}
+		if current.Username != username {
+			return common.ErrNotOwner
+		}
+		collection.Username, collection.CreatedAt, collection.UpdatedAt = current.Username, current.CreatedAt, time.Now()
+		if err := tx.Model(&current).Select("name", "updated_at").Updates(collection).Error; err != nil {
+			return err
+		}
+		return collection.saveItems(tx)
+	})
+}
+
+// Delete 成员函数，删除合集，成员本身不受影响，只有创建者可以删除
+func (collection *Collection) Delete(username string) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&collection).Error; err != nil {
+			return err
+		}
+		if collection.Username != username {
+			return common.ErrNotOwner
+		}
+		if err := tx.Where(map[string]interface{}{"collection_id": collection.ID}).Delete(&CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&collection).Error
+	})
+}
+
+// Get 成员函数，读取合集以及按顺序排列的全部成员的元信息，不会读取成员的内容
+func (collection *Collection) Get() error {
+	if err := dao.DB.Take(&collection).Error; err != nil {
+		return err
+	}
+	collection.Pastes = make([]PasteInfo, 0)
+	if err := dao.DB.Model(&Permanent{}).
+		Select("permanent.key", "permanent.lang", "permanent.size", "permanent.created_at").
+		Joins("JOIN collection_item ON collection_item.key = permanent.key").
+		Where("collection_item.collection_id = ?", collection.ID).
+		Where("permanent.delete_at IS NULL OR permanent.delete_at > ?", time.Now()).
+		Order("collection_item.position").Scan(&collection.Pastes).Error; err != nil {
+		return err
+	}
+	for i := range collection.Pastes {
+		collection.Pastes[i].Type = TypePermanent
+	}
+	return nil
+}
+
+// Collections 列出用户的全部合集，按修改时间从新到旧排列
+func Collections(username string) ([]CollectionInfo, error) {
+	collections := make([]CollectionInfo, 0)
+	if err := dao.DB.Model(&Collection{}).
+		Select("collection.id", "collection.name", "collection.created_at", "collection.updated_at",
+			"(SELECT COUNT(*) FROM collection_item WHERE collection_item.collection_id = collection.id) AS size").
+		Where(map[string]interface{}{"username": username}).
+		Order("collection.updated_at DESC").Scan(&collections).Error; err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
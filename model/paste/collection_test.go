@@ -0,0 +1,62 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"testing"
+)
+
+func TestCollection(t *testing.T) {
+	const username = "collector"
+	var keys []string
+	for _, content := range []string{"first", "second", "third"} {
+		paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: content, Username: username}}
+		assertNil(t, paste.Save())
+		keys = append(keys, paste.Key)
+	}
+
+	collection := Collection{Username: username, Name: " incident ", Keys: []string{keys[2], keys[0], keys[2]}}
+	assertNil(t, collection.Save())
+	assertEqual(t, "incident", collection.Name)
+
+	got := Collection{ID: collection.ID}
+	assertNil(t, got.Get())
+	assertEqual(t, username, got.Username)
+	assertEqual(t, 2, len(got.Pastes))
+	assertEqual(t, keys[2], got.Pastes[0].Key)
+	assertEqual(t, keys[0], got.Pastes[1].Key)
+	assertEqual(t, int64(len("third")), got.Pastes[0].Size)
+
+	update := Collection{ID: collection.ID, Name: "incident", Keys: []string{keys[1], keys[0]}}
+	if err := update.Update("someone"); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	assertNil(t, update.Update(username))
+	invalid := Collection{ID: collection.ID, Name: "incident", Keys: []string{"notexist"}}
+	if err := invalid.Update(username); !errors.Is(err, common.ErrInvalidCollectionItem) {
+		t.Fatalf("expected invalid collection item, got %v", err)
+	}
+	if err := (&Collection{Username: username, Name: ""}).Save(); !errors.Is(err, common.ErrInvalidCollectionName) {
+		t.Fatalf("expected invalid collection name, got %v", err)
+	}
+
+	// 回收站中的成员不会出现
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: keys[1]}}).Remove(username, false))
+	got = Collection{ID: collection.ID}
+	assertNil(t, got.Get())
+	assertEqual(t, 1, len(got.Pastes))
+	assertEqual(t, keys[0], got.Pastes[0].Key)
+
+	collections, err := Collections(username)
+	assertNil(t, err)
+	assertEqual(t, 1, len(collections))
+	assertEqual(t, int64(2), collections[0].Size)
+
+	if err = (&Collection{ID: collection.ID}).Delete("someone"); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	assertNil(t, (&Collection{ID: collection.ID}).Delete(username))
+	collections, err = Collections(username)
+	assertNil(t, err)
+	assertEqual(t, 0, len(collections))
+}
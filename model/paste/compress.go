@@ -0,0 +1,62 @@
+package paste
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"io"
+)
+
+const gzipEncoding = "gzip"
+
+// compress 按配置压缩内容，压缩后没有变小时保持原样
+func (paste *AbstractPaste) compress() error {
+	compression := config.Config.Compression
+	if compression.Encoding == "" || len(paste.Content) < compression.MinSize {
+		return nil
+	}
+	if compression.Encoding != gzipEncoding {
+		return fmt.Errorf("unsupported encoding %s", compression.Encoding)
+	}
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(paste.Content)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if buffer.Len() >= len(paste.Content) {
+		return nil
+	}
+
+	paste.Encoding = compression.Encoding
+	paste.EncodedContent = buffer.Bytes()
+	paste.Content = ""
+	return nil
+}
+
+// decompress 将 EncodedContent 解压到 Content 中
+func (paste *AbstractPaste) decompress() error {
+	if paste.Encoding != gzipEncoding {
+		return fmt.Errorf("unsupported encoding %s", paste.Encoding)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(paste.EncodedContent))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	paste.Content = string(content)
+	paste.Encoding, paste.EncodedContent = "", nil
+	return nil
+}
@@ -0,0 +1,107 @@
+package paste
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"github.com/PasteUs/PasteMeGoBackend/model/store"
+	"io"
+	"strings"
+	"testing"
+)
+
+func useFilesystemStore(t *testing.T) store.ContentStore {
+	fs, err := store.NewFilesystem(t.TempDir())
+	assertNil(t, err)
+	store.Default, store.Threshold = fs, 4
+	t.Cleanup(func() {
+		store.Default, store.Threshold = nil, 0
+	})
+	return fs
+}
+
+func storedContent(t *testing.T, model interface{}, key string) string {
+	var paste AbstractPaste
+	assertNil(t, dao.DB.Model(model).Where(map[string]interface{}{"key": key}).Take(&paste).Error)
+	return paste.Content
+}
+
+func TestPermanentContentStore(t *testing.T) {
+	fs := useFilesystemStore(t)
+
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "Hello World!"}}
+	assertNil(t, paste.Save())
+	assertEqual(t, "Hello World!", paste.Content)
+	assertEqual(t, "", storedContent(t, &Permanent{}, paste.Key))
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "Hello World!", got.Content)
+
+	content, err := fs.Get(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, "Hello World!", string(content))
+}
+
+func TestTemporaryContentStore(t *testing.T) {
+	fs := useFilesystemStore(t)
+
+	small := Temporary{AbstractPaste: &AbstractPaste{Content: "tiny"}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, small.Save())
+	assertEqual(t, "tiny", storedContent(t, &Temporary{}, small.Key))
+
+	paste := Temporary{AbstractPaste: &AbstractPaste{Content: "Hello World!"}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, paste.Save())
+	assertEqual(t, "", storedContent(t, &Temporary{}, paste.Key))
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "Hello World!", got.Content)
+
+	if _, err := fs.Get(paste.Key); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expect %v, got %v", store.ErrNotFound, err)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	content := strings.Repeat("2021-01-01 00:00:00 INFO hello world\n", 100)
+
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: content}}
+	assertNil(t, paste.Save())
+	assertEqual(t, content, paste.Content)
+
+	var stored AbstractPaste
+	assertNil(t, dao.DB.Model(&Permanent{}).Where(map[string]interface{}{"key": paste.Key}).Take(&stored).Error)
+	assertEqual(t, "gzip", stored.Encoding)
+	assertEqual(t, "", stored.Content)
+	if len(stored.EncodedContent) >= len(content) {
+		t.Fatalf("expect compressed content, got %d bytes", len(stored.EncodedContent))
+	}
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, content, got.GetContent())
+	assertEqual(t, "", got.GetEncoding())
+
+	raw := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, AcceptEncoding: "gzip"}}
+	assertNil(t, raw.Get(""))
+	assertEqual(t, "gzip", raw.GetEncoding())
+	reader, err := gzip.NewReader(bytes.NewReader(raw.GetEncodedContent()))
+	assertNil(t, err)
+	inflated, err := io.ReadAll(reader)
+	assertNil(t, err)
+	assertEqual(t, content, string(inflated))
+}
+
+func TestCompressionWithContentStore(t *testing.T) {
+	useFilesystemStore(t)
+	content := strings.Repeat("Hello World!\n", 100)
+
+	paste := Temporary{AbstractPaste: &AbstractPaste{Content: content}, ExpireSecond: 60, ExpireCount: 2}
+	assertNil(t, paste.Save())
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, content, got.GetContent())
+}
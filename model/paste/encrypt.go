@@ -0,0 +1,119 @@
+package paste
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/common/password"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+)
+
+const (
+	encryptionPassword = "password" // 使用由密码派生的密钥加密
+	encryptionMaster   = "master"   // 使用配置中的主密钥加密
+)
+
+// encrypt 使用 AES-256-GCM 加密内容
+// 设置了密码时密文格式为 salt | nonce | ciphertext，否则在配置了主密钥时为 nonce | ciphertext
+func (paste *AbstractPaste) encrypt(secret string) error {
+	var (
+		key    []byte
+		prefix []byte
+	)
+	switch {
+	case secret != "":
+		salt, err := password.Salt()
+		if err != nil {
+			return err
+		}
+		key, prefix = password.DeriveKey(secret, salt), salt
+		paste.Encryption = encryptionPassword
+	case config.Config.Encryption.MasterKey != "":
+		key = masterKey()
+		paste.Encryption = encryptionMaster
+	default:
+		return nil
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	plaintext := paste.EncodedContent
+	if paste.Encoding == "" {
+		plaintext = []byte(paste.Content)
+	}
+	envelope := append(prefix, nonce...)
+	paste.EncodedContent = aead.Seal(envelope, nonce, plaintext, []byte(paste.Key))
+	paste.Content = ""
+	return nil
+}
+
+// decrypt 解密内容，使用密码加密且无法解密时说明密码错误
+func (paste *AbstractPaste) decrypt(secret string) error {
+	var (
+		key      []byte
+		envelope = paste.EncodedContent
+	)
+	switch paste.Encryption {
+	case "":
+		return nil
+	case encryptionPassword:
+		if secret == "" || len(envelope) < password.SaltLength {
+			return common.ErrWrongPassword
+		}
+		key = password.DeriveKey(secret, envelope[:password.SaltLength])
+		envelope = envelope[password.SaltLength:]
+	case encryptionMaster:
+		if config.Config.Encryption.MasterKey == "" {
+			return errors.New("master key not configured")
+		}
+		key = masterKey()
+	default:
+		return fmt.Errorf("unsupported encryption %s", paste.Encryption)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	if len(envelope) < aead.NonceSize() {
+		return errors.New("invalid encrypted content")
+	}
+	plaintext, err := aead.Open(nil, envelope[:aead.NonceSize()], envelope[aead.NonceSize():], []byte(paste.Key))
+	if err != nil {
+		if paste.Encryption == encryptionPassword {
+			return common.ErrWrongPassword
+		}
+		return err
+	}
+
+	if paste.Encoding == "" {
+		paste.Content, paste.EncodedContent = string(plaintext), nil
+	} else {
+		paste.EncodedContent = plaintext
+	}
+	return nil
+}
+
+func masterKey() []byte {
+	key := sha256.Sum256([]byte(config.Config.Encryption.MasterKey))
+	return key[:]
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
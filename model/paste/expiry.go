@@ -0,0 +1,172 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+// ExpiryExtension 创建者每一次延长过期时间或转为永久的记录
+type ExpiryExtension struct {
+	ID          uint       `json:"-" gorm:"primaryKey"`
+	Key         string     `json:"-" gorm:"type:varchar(16);index"`
+	Username    string     `json:"-" gorm:"type:varchar(16)"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`   // 延长后的过期时间，永久的一贴为设置的删除时间
+	ExpireCount uint64     `json:"expire_count,omitempty"` // 延长后剩余的查看次数
+	IdleSecond  uint64     `json:"idle_second,omitempty"`  // 延长后的闲置过期时间
+	Permanent   bool       `json:"permanent"`              // 是否为永久的一贴
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ExpiryOptions 延长过期时间的参数，为零的字段保持不变，只能延长，不能缩短
+type ExpiryOptions struct {
+	ExpireAt    *time.Time // 新的过期时刻
+	IdleSecond  uint64     // 新的闲置过期时间，只对已经设置了闲置过期的一贴有效
+	ExpireCount uint64     // 新的剩余查看次数
+	Permanent   bool       // 转为永久的一贴，对永久的一贴则是取消删除时间
+	DeleteAt    *time.Time // 永久的一贴的删除时间，只能推迟
+}
+
+// isTemporaryKey 0 开头的 key 属于 Temporary，除非已经转为永久的一贴
+func isTemporaryKey(tx *gorm.DB, key string) (bool, error) {
+	if !strings.HasPrefix(key, "0") {
+		return false, nil
+	}
+	var count int64
+	if err := tx.Unscoped().Model(&Permanent{}).Where(map[string]interface{}{"key": key}).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// IsTemporaryKey 判断 key 是否属于自我销毁的一贴，转为永久的一贴保留了 0 开头的 key
+func IsTemporaryKey(key string) (bool, error) {
+	return isTemporaryKey(dao.DB, key)
+}
+
+// Extend 成员函数，延长自我销毁的一贴的过期时间与查看次数，或者保留原来的 key 转为永久的一贴
+// 只有创建者可以修改，每一次修改都会留下一条 ExpiryExtension
+func (paste *Temporary) Extend(username string, options ExpiryOptions) error {
+	extension := ExpiryExtension{Key: paste.Key, Username: username}
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&paste).Error; err != nil {
+			return err
+		}
+		if paste.Expired() {
+			return gorm.ErrRecordNotFound // 留给后台清理
+		}
+		if paste.Username == "" || paste.Username != username {
+			return common.ErrNotOwner
+		}
+
+		if options.Permanent {
+			if err := paste.convert(tx, options.DeleteAt); err != nil {
+				return err
+			}
+			extension.ExpiresAt, extension.Permanent = options.DeleteAt, true
+			return tx.Create(&extension).Error
+		}
+
+		if err := paste.extend(options); err != nil {
+			return err
+		}
+		if err := tx.Model(&paste).Updates(map[string]interface{}{
+			"expire_second": paste.ExpireSecond,
+			"expire_at":     paste.ExpireAt,
+			"idle_second":   paste.IdleSecond,
+			"expire_count":  paste.ExpireCount,
+			"expires_at":    paste.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+		expiresAt := paste.ExpiresAt
+		extension.ExpiresAt, extension.ExpireCount, extension.IdleSecond = &expiresAt, paste.ExpireCount, paste.IdleSecond
+		return tx.Create(&extension).Error
+	})
+}
+
+// extend 按 options 修改过期时间与查看次数，任何一项比当前的更早或更少时返回 common.ErrNotExtended
+func (paste *Temporary) extend(options ExpiryOptions) error {
+	if options.ExpireAt != nil {
+		current := paste.ExpireAt
+		if current == nil && paste.IdleSecond == 0 {
+			current = &paste.ExpiresAt // 旧记录只有 ExpiresAt
+		}
+		if current == nil || options.ExpireAt.Before(*current) {
+			return common.ErrNotExtended // 只有闲置过期的一贴再加上过期时刻反而可能更早过期
+		}
+		expireAt := *options.ExpireAt
+		paste.ExpireAt = &expireAt
+		paste.ExpireSecond = uint64(expireAt.Sub(paste.CreatedAt).Seconds())
+	}
+	if options.IdleSecond > 0 {
+		if paste.IdleSecond == 0 || options.IdleSecond < paste.IdleSecond {
+			return common.ErrNotExtended
+		}
+		paste.IdleSecond = options.IdleSecond
+	}
+	if options.ExpireCount > 0 {
+		if options.ExpireCount < paste.ExpireCount {
+			return common.ErrNotExtended
+		}
+		paste.ExpireCount = options.ExpireCount
+	}
+	// 闲置过期从本次修改重新计时
+	if expiresAt := paste.deadline(time.Now()); expiresAt.After(paste.ExpiresAt) {
+		paste.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+// convert 在事务中将自我销毁的一贴移动为永久的一贴，key 与内容都不变，标签与全文索引随之保留
+func (paste *Temporary) convert(tx *gorm.DB, deleteAt *time.Time) error {
+	permanent := Permanent{AbstractPaste: paste.AbstractPaste, Revision: 1, DeleteAt: deleteAt}
+	if err := tx.Create(&permanent).Error; err != nil {
+		return err
+	}
+	return tx.Where(map[string]interface{}{"key": paste.Key}).Delete(&Temporary{AbstractPaste: &AbstractPaste{}}).Error
+}
+
+// Extend 成员函数，推迟或取消永久的一贴的删除时间，只有创建者可以修改
+func (paste *Permanent) Extend(username string, options ExpiryOptions) error {
+	defer readCache.invalidate(paste.Key)
+	extension := ExpiryExtension{Key: paste.Key, Username: username, Permanent: true}
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&paste).Error; err != nil {
+			return err
+		}
+		if paste.Scheduled() {
+			return gorm.ErrRecordNotFound
+		}
+		if paste.Username == "" || paste.Username != username {
+			return common.ErrNotOwner
+		}
+
+		switch {
+		case options.Permanent:
+			paste.DeleteAt = nil
+		case options.DeleteAt != nil && paste.DeleteAt != nil && !options.DeleteAt.Before(*paste.DeleteAt):
+			deleteAt := *options.DeleteAt
+			paste.DeleteAt = &deleteAt
+		default:
+			return common.ErrNotExtended // 没有删除时间的一贴已经是永久保留的
+		}
+		if err := tx.Model(&paste).Update("delete_at", paste.DeleteAt).Error; err != nil {
+			return err
+		}
+		extension.ExpiresAt = paste.DeleteAt
+		return tx.Create(&extension).Error
+	})
+}
+
+// Extensions 列出一贴的全部延长记录，按时间从旧到新排列
+func Extensions(key string) ([]ExpiryExtension, error) {
+	extensions := make([]ExpiryExtension, 0)
+	if err := dao.DB.Where(map[string]interface{}{"key": key}).Order("id").Find(&extensions).Error; err != nil {
+		return nil, err
+	}
+	return extensions, nil
+}
@@ -0,0 +1,131 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestTemporaryExtend(t *testing.T) {
+	paste := Temporary{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "incident log", Username: "extender"}, ExpireSecond: 60, ExpireCount: 2}
+	assertNil(t, paste.Save())
+	extend := func(username string, options ExpiryOptions) error {
+		return (&Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Extend(username, options)
+	}
+
+	later := time.Now().Add(2 * time.Hour)
+	if err := extend("bob", ExpiryOptions{ExpireAt: &later}); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	earlier := time.Now().Add(time.Second)
+	if err := extend("extender", ExpiryOptions{ExpireAt: &earlier}); !errors.Is(err, common.ErrNotExtended) {
+		t.Fatalf("expected not extended, got %v", err)
+	}
+	if err := extend("extender", ExpiryOptions{ExpireCount: 1}); !errors.Is(err, common.ErrNotExtended) {
+		t.Fatalf("expected not extended, got %v", err)
+	}
+	if err := extend("extender", ExpiryOptions{IdleSecond: 60}); !errors.Is(err, common.ErrNotExtended) {
+		t.Fatalf("idle expiry on a fixed deadline should not be added, got %v", err)
+	}
+
+	assertNil(t, extend("extender", ExpiryOptions{ExpireAt: &later, ExpireCount: 5}))
+	stored := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, dao.DB.Take(&stored).Error)
+	assertEqual(t, true, stored.ExpiresAt.Equal(later))
+	assertEqual(t, uint64(5), stored.ExpireCount)
+	assertEqual(t, true, stored.ExpireSecond > 60*60)
+
+	extensions, err := Extensions(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, 1, len(extensions))
+	assertEqual(t, uint64(5), extensions[0].ExpireCount)
+	assertEqual(t, true, extensions[0].ExpiresAt.Equal(later))
+	assertEqual(t, false, extensions[0].CreatedAt.IsZero())
+}
+
+func TestTemporaryConvert(t *testing.T) {
+	paste := Temporary{AbstractPaste: &AbstractPaste{Lang: "go", Content: "package main", Password: "secret", Username: "converter"}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, paste.Save())
+	_, err := SetTags(paste.Key, "converter", []string{"incident-7"})
+	assertNil(t, err)
+	temporary, err := IsTemporaryKey(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, true, temporary)
+
+	assertNil(t, (&Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Extend("converter", ExpiryOptions{Permanent: true}))
+	temporary, err = IsTemporaryKey(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, false, temporary)
+	assertEqual(t, false, exist(paste.Key, &Temporary{AbstractPaste: &AbstractPaste{}}))
+
+	// key 不变，内容不需要重新加密，读取不再消耗查看次数
+	for i := 0; i < 2; i++ {
+		got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+		assertNil(t, got.Get("secret"))
+		assertEqual(t, "package main", got.Content)
+	}
+	meta, err := ReadMeta(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, TypePermanent, meta.Type)
+	assertEqual(t, true, meta.HasPassword)
+	tags, err := GetTags(paste.Key, "converter")
+	assertNil(t, err)
+	assertEqual(t, 1, len(tags))
+
+	// 已经是永久保留的一贴，没有可以推迟的删除时间
+	deleteAt := time.Now().Add(time.Hour)
+	if err = (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Extend("converter", ExpiryOptions{DeleteAt: &deleteAt}); !errors.Is(err, common.ErrNotExtended) {
+		t.Fatalf("expected not extended, got %v", err)
+	}
+
+	extensions, err := Extensions(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, 1, len(extensions))
+	assertEqual(t, true, extensions[0].Permanent)
+
+	if _, err = destroy([]string{paste.Key}); err != nil {
+		t.Fatal(err)
+	}
+	extensions, err = Extensions(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, 0, len(extensions))
+}
+
+func TestPermanentExtend(t *testing.T) {
+	deleteAt := time.Now().Add(time.Hour)
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "sprint board", Username: "scheduler"}, DeleteAt: &deleteAt}
+	assertNil(t, paste.Save())
+	extend := func(username string, options ExpiryOptions) error {
+		return (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Extend(username, options)
+	}
+
+	earlier, later := deleteAt.Add(-time.Minute), deleteAt.Add(24*time.Hour)
+	if err := extend("bob", ExpiryOptions{DeleteAt: &later}); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	if err := extend("scheduler", ExpiryOptions{DeleteAt: &earlier}); !errors.Is(err, common.ErrNotExtended) {
+		t.Fatalf("expected not extended, got %v", err)
+	}
+	assertNil(t, extend("scheduler", ExpiryOptions{DeleteAt: &later}))
+	meta, err := ReadMeta(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, true, meta.ExpiresAt.Equal(later))
+
+	assertNil(t, extend("scheduler", ExpiryOptions{Permanent: true}))
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, true, got.DeleteAt == nil)
+
+	extensions, err := Extensions(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, 2, len(extensions))
+	assertEqual(t, true, extensions[1].ExpiresAt == nil)
+
+	assertNil(t, dao.DB.Model(&paste).Update("delete_at", time.Now().Add(-time.Second)).Error)
+	if err = extend("scheduler", ExpiryOptions{Permanent: true}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"time"
+)
+
+// ForkInfo fork 出的永久 paste，不包含内容
+type ForkInfo struct {
+	Key       string    `json:"key" example:"a1b2c3d4"`
+	Lang      string    `json:"lang" example:"plain"`
+	Username  string    `json:"username" example:"alice"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (paste *AbstractPaste) GetParentKey() string {
+	return paste.ParentKey
+}
+
+// Forks 列出从 key fork 出的永久 paste，按创建时间从旧到新排列，username 不为空时只列出该用户的 fork
+// 自我销毁的 fork 不会列出，否则任何人都可以通过列表读取并消耗它的查看次数
+func Forks(key string, username string) ([]ForkInfo, error) {
+	forks := make([]ForkInfo, 0)
+	query := dao.DB.Model(&Permanent{}).Select("key", "lang", "username", "created_at").
+		Where(map[string]interface{}{"parent_key": key})
+	if username != "" {
+		query = query.Where(map[string]interface{}{"username": username})
+	}
+	if err := query.Order("created_at").Scan(&forks).Error; err != nil {
+		return nil, err
+	}
+	return forks, nil
+}
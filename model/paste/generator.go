@@ -1,25 +1,107 @@
 package paste
 
 import (
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"math/big"
+	mathrand "math/rand"
+	"regexp"
+	"strings"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano()) // 连续生成随机数，以当前纳秒数作为随机数种子
-}
+const (
+	minKeyLength = 3  // 自定义 key 的最短长度
+	maxKeyLength = 16 // 与 key 字段的 varchar(16) 一致
+	maxAttempts  = 10 // key 冲突时的最大重试次数
+)
 
 var (
-	charset            = []rune("qwertyuiopasdfghjklzxcvbnm0123456789")
-	charsetWithoutZero = []rune("qwertyuiopasdfghjklzxcvbnm123456789")
+	keyLength          int
+	charset            []rune
+	charsetWithoutZero []rune
+	randomIndex        func(n int) int
+	keyPattern         *regexp.Regexp
+	legacyKeyPattern   = regexp.MustCompile("^[0-9a-z]{8}$")
+	vanityKeyPattern   = regexp.MustCompile("^[1-9a-z][0-9a-z-]*[0-9a-z]$") // 不以 0 开头，避免与 Temporary 混淆
+	caseSensitiveKey   bool
+	alphanumeric       = regexp.MustCompile("^[0-9A-Za-z]+$")
 )
 
+func init() {
+	if err := setKeyFormat(config.Config.Key); err != nil {
+		panic(err.Error())
+	}
+}
+
+// setKeyFormat 按配置设置 key 的长度、字符集和随机数来源
+func setKeyFormat(key config.Key) error {
+	if key.Length < 4 || key.Length > maxKeyLength {
+		return fmt.Errorf("key length should be in [4, %d], got %d", maxKeyLength, key.Length)
+	}
+	// Temporary 的 key 以 0 开头，Permanent 的 key 不以 0 开头，字符集中必须包含 0
+	if !strings.ContainsRune(key.Alphabet, '0') || len([]rune(key.Alphabet)) < 2 {
+		return fmt.Errorf("key alphabet should contain '0' and at least one other character, got %q", key.Alphabet)
+	}
+	// "-" 用于自定义 key，"_" 用于外部存储中的修订版本，生成的 key 只使用字母与数字
+	if !alphanumeric.MatchString(key.Alphabet) {
+		return fmt.Errorf("key alphabet should only contain letters and digits, got %q", key.Alphabet)
+	}
+
+	switch key.Source {
+	case "crypto":
+		randomIndex = cryptoRandomIndex
+	case "math":
+		randomIndex = mathrand.Intn
+	default:
+		return fmt.Errorf("unknown key source %q", key.Source)
+	}
+
+	keyLength = key.Length
+	charset = []rune(key.Alphabet)
+	charsetWithoutZero = []rune(strings.ReplaceAll(key.Alphabet, "0", ""))
+	keyPattern = regexp.MustCompile(fmt.Sprintf("^[%s]{%d}$", regexp.QuoteMeta(key.Alphabet), key.Length))
+	caseSensitiveKey = strings.ToLower(key.Alphabet) != key.Alphabet // 含有大写字母时转为小写会得到不同的 key
+	return nil
+}
+
+func cryptoRandomIndex(n int) int {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(fmt.Sprintf("read crypto random failed, error = \"%s\"", err.Error()))
+	}
+	return int(index.Int64())
+}
+
+// NormalizeKey 字符集只有小写字母时统一转为小写，旧版本的 key 也都是小写
+func NormalizeKey(key string) string {
+	if caseSensitiveKey {
+		return key
+	}
+	return strings.ToLower(key)
+}
+
+// ValidKeyLength 判断 key 的长度是否在允许的范围内
+func ValidKeyLength(key string) bool {
+	return len(key) >= minKeyLength && len(key) <= maxKeyLength
+}
+
+// ValidKeyFormat 判断 key 是否符合当前配置、旧版本或自定义 key 的格式
+func ValidKeyFormat(key string) bool {
+	return keyPattern.MatchString(key) || legacyKeyPattern.MatchString(key) || ValidVanityKey(key)
+}
+
+// ValidVanityKey 判断自定义 key 是否合法：小写字母、数字与 -，不以 0 或 - 开头，不以 - 结尾
+func ValidVanityKey(key string) bool {
+	return ValidKeyLength(key) && vanityKeyPattern.MatchString(key)
+}
+
 func getOne(cs []rune) rune {
-	return cs[rand.Intn(len(cs))]
+	return cs[randomIndex(len(cs))]
 }
 
-func _generator(length int, zeroFirst bool) string {
-	ret := make([]rune, length)
+func generator(zeroFirst bool) string {
+	ret := make([]rune, keyLength)
 
 	if zeroFirst {
 		ret[0] = '0'
@@ -27,16 +109,8 @@ func _generator(length int, zeroFirst bool) string {
 		ret[0] = getOne(charsetWithoutZero)
 	}
 
-	for i := 1; i < length; i++ {
+	for i := 1; i < keyLength; i++ {
 		ret[i] = getOne(charset)
 	}
 	return string(ret)
 }
-
-func generator(length int, zeroFirst bool, model interface{}) string {
-	str := _generator(length, zeroFirst)
-	for exist(str, model) {
-		str = _generator(length, zeroFirst)
-	}
-	return str
-}
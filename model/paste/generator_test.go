@@ -0,0 +1,167 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"strings"
+	"testing"
+)
+
+func useKeyFormat(t *testing.T, key config.Key) {
+	assertNil(t, setKeyFormat(key))
+	t.Cleanup(func() {
+		assertNil(t, setKeyFormat(config.Config.Key))
+	})
+}
+
+// useFixedIndex 让随机数来源依次返回给定的下标，用完后一直返回最后一个
+func useFixedIndex(t *testing.T, indexes ...int) {
+	source := randomIndex
+	randomIndex = func(n int) int {
+		index := indexes[0]
+		if len(indexes) > 1 {
+			indexes = indexes[1:]
+		}
+		return index % n
+	}
+	t.Cleanup(func() {
+		randomIndex = source
+	})
+}
+
+func TestSetKeyFormat(t *testing.T) {
+	for _, key := range []config.Key{
+		{Length: 3, Alphabet: "0123456789", Source: "crypto"},
+		{Length: 17, Alphabet: "0123456789", Source: "crypto"},
+		{Length: 8, Alphabet: "abcdef", Source: "crypto"},
+		{Length: 8, Alphabet: "0", Source: "crypto"},
+		{Length: 8, Alphabet: "0123456789-", Source: "crypto"},
+		{Length: 8, Alphabet: "0123456789", Source: "unknown"},
+	} {
+		if err := setKeyFormat(key); err == nil {
+			t.Errorf("key format %+v should be rejected", key)
+		}
+	}
+	assertNil(t, setKeyFormat(config.Config.Key))
+}
+
+func TestGenerator(t *testing.T) {
+	useKeyFormat(t, config.Key{Length: 12, Alphabet: "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", Source: "crypto"})
+
+	for i := 0; i < 100; i++ {
+		temporary, permanent := generator(true), generator(false)
+		assertEqual(t, 12, len(temporary))
+		assertEqual(t, 12, len(permanent))
+		assertEqual(t, true, strings.HasPrefix(temporary, "0"))
+		assertEqual(t, false, strings.HasPrefix(permanent, "0"))
+		assertEqual(t, true, ValidKeyFormat(temporary))
+		assertEqual(t, true, ValidKeyFormat(permanent))
+	}
+
+	assertEqual(t, true, ValidKeyFormat("abcd1234"))  // 旧版本的 key 依然有效
+	assertEqual(t, false, ValidKeyFormat("abc_1234")) // 不在任何格式的字符集中
+	assertEqual(t, "AbC123dEf456", NormalizeKey("AbC123dEf456"))
+}
+
+func TestNormalizeKey(t *testing.T) {
+	assertEqual(t, "abcd1234", NormalizeKey("ABCD1234"))
+}
+
+func TestUppercaseAlphabet(t *testing.T) {
+	useKeyFormat(t, config.Key{Length: 8, Alphabet: "0123456789ABCDEF", Source: "crypto"})
+	useFixedIndex(t, 10, 11, 12, 13, 14, 15, 10, 11) // 保证生成的 key 中含有大写字母
+
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "uppercase key"}}
+	assertNil(t, paste.Save())
+	key := NormalizeKey(paste.Key)
+	assertEqual(t, paste.Key, key)
+	assertEqual(t, true, ValidKeyFormat(key))
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "uppercase key", got.Content)
+}
+
+func TestKeyCollision(t *testing.T) {
+	useFixedIndex(t, 1)
+
+	first := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "first"}}
+	assertNil(t, first.Save())
+	t.Cleanup(func() {
+		assertNil(t, dao.DB.Unscoped().Delete(&first).Error)
+	})
+
+	// 随机数来源固定时每次生成的 key 都相同，重试次数用完后返回 ErrKeyCollision
+	second := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "second"}}
+	if err := second.Save(); !errors.Is(err, common.ErrKeyCollision) {
+		t.Fatalf("expected key collision, got %v", err)
+	}
+	assertEqual(t, "second", second.Content)
+}
+
+func TestKeyCollisionRetry(t *testing.T) {
+	fs := useFilesystemStore(t)
+	useFixedIndex(t, 2)
+
+	first := Temporary{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "first paste"}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, first.Save())
+
+	// 第一次生成的 key 与 first 冲突，第二次生成新的 key
+	indexes := make([]int, keyLength)
+	for i := range indexes {
+		indexes[i] = 2
+	}
+	useFixedIndex(t, append(indexes, 3)...)
+
+	second := Temporary{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "second paste"}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, second.Save())
+	t.Cleanup(func() {
+		assertNil(t, second.Delete())
+	})
+	if first.Key == second.Key {
+		t.Fatalf("key %s should be regenerated after collision", second.Key)
+	}
+
+	// 冲突的写入不能覆盖外部存储中已有的内容
+	body, err := fs.Get(first.Key)
+	assertNil(t, err)
+	assertEqual(t, true, len(body) > 0)
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: first.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "first paste", got.Content)
+}
+
+func TestVanityKey(t *testing.T) {
+	for key, expect := range map[string]bool{
+		"deploy-notes":      true,
+		"abc":               true,
+		"ab":                false,
+		"0deploy":           false, // 0 开头的 key 属于 Temporary
+		"-deploy":           false,
+		"deploy-":           false,
+		"Deploy":            false,
+		"deploy_notes":      false,
+		"deploy-notes-2024": false, // 超过 16 位
+	} {
+		assertEqual(t, expect, ValidVanityKey(key))
+	}
+
+	first := Permanent{AbstractPaste: &AbstractPaste{Key: "deploy-notes", Lang: "plain", Content: "first"}}
+	assertNil(t, first.Save())
+	t.Cleanup(func() {
+		assertNil(t, dao.DB.Unscoped().Delete(&first).Error)
+	})
+	assertEqual(t, "deploy-notes", first.Key)
+
+	second := Permanent{AbstractPaste: &AbstractPaste{Key: "deploy-notes", Lang: "plain", Content: "second"}}
+	if err := second.Save(); !errors.Is(err, common.ErrKeyConflict) {
+		t.Fatalf("expected key conflict, got %v", err)
+	}
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: "deploy-notes"}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "first", got.Content)
+}
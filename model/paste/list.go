@@ -0,0 +1,205 @@
+package paste
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	TypePermanent = "permanent"
+	TypeTemporary = "temporary"
+
+	SortCreatedAt = "created_at"
+	SortSize      = "size"
+
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PasteInfo 列表中的一贴，只有元信息，不包含内容
+type PasteInfo struct {
+	Key            string     `json:"key" example:"a1b2c3d4"`
+	Type           string     `json:"type" example:"permanent"` // permanent 或 temporary
+	Lang           string     `json:"lang" example:"plain"`
+	Size           int64      `json:"size" example:"12"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`      // 自我销毁的时间，永久的一贴为创建者设置的删除时间
+	RemainingViews *uint64    `json:"remaining_views,omitempty"` // 剩余的查看次数，永久的一贴没有
+	Tags           []string   `json:"tags,omitempty"`
+}
+
+// ListOptions 列表的过滤、排序与分页条件，零值表示不过滤
+type ListOptions struct {
+	Lang          string
+	Tag           string
+	Type          string // permanent、temporary，为空时两者都列出
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Sort          string // created_at 或 size，默认为 created_at
+	Ascending     bool   // 默认从新到旧、从大到小
+	Limit         int
+	Cursor        string // 上一页返回的 next_cursor
+}
+
+// cursor 上一页最后一项的排序字段与 key，两者一起保证顺序唯一
+type cursor struct {
+	CreatedAt time.Time `json:"c"`
+	Size      int64     `json:"s"`
+	Key       string    `json:"k"`
+}
+
+func encodeCursor(info PasteInfo) string {
+	data, _ := json.Marshal(cursor{CreatedAt: info.CreatedAt, Size: info.Size, Key: info.Key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil || c.Key == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// List 列出用户创建的一贴，只查询元信息，不会读取内容，也不会消耗自我销毁的一贴的查看次数
+// 返回的 next 不为空时可以作为下一页的 Cursor
+func List(username string, options ListOptions) (pastes []PasteInfo, next string, err error) {
+	if options.Sort != SortSize {
+		options.Sort = SortCreatedAt
+	}
+	if options.Limit <= 0 || options.Limit > MaxListLimit {
+		options.Limit = DefaultListLimit
+	}
+	var after *cursor
+	if options.Cursor != "" {
+		if after, err = decodeCursor(options.Cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	pastes = make([]PasteInfo, 0, options.Limit+1)
+	if options.Type != TypeTemporary {
+		var permanents []Permanent
+		if err = listQuery(&Permanent{}, username, options, after).Select("key", "lang", "size", "created_at", "delete_at").
+			Where("delete_at IS NULL OR delete_at > ?", time.Now()).Find(&permanents).Error; err != nil {
+			return nil, "", err
+		}
+		for _, paste := range permanents {
+			pastes = append(pastes, PasteInfo{
+				Key: paste.Key, Type: TypePermanent, Lang: paste.Lang, Size: paste.Size, CreatedAt: paste.CreatedAt,
+				ExpiresAt: paste.DeleteAt,
+			})
+		}
+	}
+	if options.Type != TypePermanent {
+		var temporaries []Temporary
+		if err = listQuery(&Temporary{}, username, options, after).
+			Select("key", "lang", "size", "created_at", "expires_at", "expire_count").
+			Where("expires_at > ? AND expire_count > 0", time.Now()).Find(&temporaries).Error; err != nil {
+			return nil, "", err
+		}
+		for _, paste := range temporaries {
+			expiresAt, remaining := paste.ExpiresAt, paste.ExpireCount
+			pastes = append(pastes, PasteInfo{
+				Key: paste.Key, Type: TypeTemporary, Lang: paste.Lang, Size: paste.Size, CreatedAt: paste.CreatedAt,
+				ExpiresAt: &expiresAt, RemainingViews: &remaining,
+			})
+		}
+	}
+
+	// 两张表各自取了 Limit + 1 条，合并排序后截取
+	sort.SliceStable(pastes, func(i, j int) bool {
+		if options.Ascending {
+			return less(pastes[i], pastes[j], options)
+		}
+		return less(pastes[j], pastes[i], options)
+	})
+	if len(pastes) > options.Limit {
+		pastes = pastes[:options.Limit]
+		next = encodeCursor(pastes[len(pastes)-1])
+	}
+	if err = fillTags(username, pastes); err != nil {
+		return nil, "", err
+	}
+	return pastes, next, nil
+}
+
+// less 按排序字段比较，相同时按 key 比较
+func less(a PasteInfo, b PasteInfo, options ListOptions) bool {
+	if options.Sort == SortSize && a.Size != b.Size {
+		return a.Size < b.Size
+	}
+	if options.Sort == SortCreatedAt && !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.Key < b.Key
+}
+
+// listQuery 构造单张表的查询，只选择元信息字段
+func listQuery(model interface{}, username string, options ListOptions, after *cursor) *gorm.DB {
+	query := dao.DB.Model(model).Select("key", "lang", "size", "created_at").
+		Where(map[string]interface{}{"username": username})
+	if options.Lang != "" {
+		query = query.Where(map[string]interface{}{"lang": options.Lang})
+	}
+	if options.Tag != "" {
+		query = query.Where("key IN (?)", dao.DB.Model(&PasteTag{}).Select("key").
+			Where(map[string]interface{}{"username": username, "name": strings.ToLower(options.Tag)}))
+	}
+	if !options.CreatedAfter.IsZero() {
+		query = query.Where("created_at >= ?", options.CreatedAfter)
+	}
+	if !options.CreatedBefore.IsZero() {
+		query = query.Where("created_at < ?", options.CreatedBefore)
+	}
+
+	column := clause.Column{Name: options.Sort}
+	key := clause.Column{Name: "key"}
+	operator := "<"
+	if options.Ascending {
+		operator = ">"
+	}
+	if after != nil {
+		var value interface{} = after.CreatedAt
+		if options.Sort == SortSize {
+			value = after.Size
+		}
+		query = query.Where("? "+operator+" ? OR (? = ? AND ? "+operator+" ?)", column, value, column, value, key, after.Key)
+	}
+	return query.Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: column, Desc: !options.Ascending},
+		{Column: key, Desc: !options.Ascending},
+	}}).Limit(options.Limit + 1)
+}
+
+// fillTags 填入列表中每一贴的标签
+func fillTags(username string, pastes []PasteInfo) error {
+	if len(pastes) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(pastes))
+	for _, paste := range pastes {
+		keys = append(keys, paste.Key)
+	}
+	tags, err := tagsOf(username, keys)
+	if err != nil {
+		return err
+	}
+	for i := range pastes {
+		pastes[i].Tags = tags[pastes[i].Key]
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	const username = "lister"
+	var keys []string
+	for _, content := range []string{"a", "bbb", "cc"} {
+		paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: content, Username: username}}
+		assertNil(t, paste.Save())
+		keys = append(keys, paste.Key)
+	}
+	temporary := Temporary{AbstractPaste: &AbstractPaste{Lang: "go", Content: "package main", Username: username},
+		ExpireSecond: 60, ExpireCount: 3}
+	assertNil(t, temporary.Save())
+
+	pastes, next, err := List(username, ListOptions{})
+	assertNil(t, err)
+	assertEqual(t, 4, len(pastes))
+	assertEqual(t, "", next)
+	for _, paste := range pastes {
+		if paste.Key == temporary.Key {
+			assertEqual(t, TypeTemporary, paste.Type)
+			assertEqual(t, uint64(3), *paste.RemainingViews)
+			assertEqual(t, int64(len("package main")), paste.Size)
+		}
+	}
+
+	// 列出不会消耗查看次数
+	stored := Temporary{}
+	assertNil(t, dao.DB.Where(map[string]interface{}{"key": temporary.Key}).Take(&stored).Error)
+	assertEqual(t, uint64(3), stored.ExpireCount)
+
+	pastes, _, err = List(username, ListOptions{Lang: "go"})
+	assertNil(t, err)
+	assertEqual(t, 1, len(pastes))
+	assertEqual(t, temporary.Key, pastes[0].Key)
+
+	// 按大小升序分页
+	var sizes []int64
+	options := ListOptions{Type: TypePermanent, Sort: SortSize, Ascending: true, Limit: 2}
+	for {
+		pastes, next, err = List(username, options)
+		assertNil(t, err)
+		for _, paste := range pastes {
+			assertEqual(t, TypePermanent, paste.Type)
+			sizes = append(sizes, paste.Size)
+		}
+		if next == "" {
+			break
+		}
+		options.Cursor = next
+	}
+	assertEqual(t, 3, len(sizes))
+	assertEqual(t, int64(1), sizes[0])
+	assertEqual(t, int64(2), sizes[1])
+	assertEqual(t, int64(3), sizes[2])
+
+	if _, _, err = List(username, ListOptions{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected invalid cursor, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"time"
+)
+
+// metaColumns 读取元信息需要的字段，不包含内容
+var metaColumns = []string{"key", "lang", "size", "password", "encryption", "encrypted", "created_at"}
+
+// Meta 一贴的元信息，不包含内容
+type Meta struct {
+	Key            string     `json:"key" example:"0a1b2c3d"`
+	Type           string     `json:"type" example:"temporary"`
+	Lang           string     `json:"lang" example:"plain"`
+	Size           int64      `json:"size" example:"12"`            // 原始内容的字节数
+	HasPassword    bool       `json:"has_password" example:"false"` // 读取时是否需要密码
+	Encrypted      bool       `json:"encrypted" example:"false"`    // 是否为客户端加密的内容
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`      // 自我销毁的时间，永久的一贴为创建者设置的删除时间
+	RemainingViews *uint64    `json:"remaining_views,omitempty"` // 剩余的查看次数，永久的一贴没有
+}
+
+// hasPassword 设置了密码时内容使用密码加密，旧记录保存了密码的哈希
+func (paste *AbstractPaste) hasPassword() bool {
+	return paste.Encryption == encryptionPassword || paste.Password != ""
+}
+
+func (paste *AbstractPaste) meta(kind string) *Meta {
+	return &Meta{
+		Key: paste.Key, Type: kind, Lang: paste.Lang, Size: paste.Size,
+		HasPassword: paste.hasPassword(), Encrypted: paste.Encrypted, CreatedAt: paste.CreatedAt,
+	}
+}
+
+// ReadMeta 读取一贴的元信息，不读取内容、不校验密码，也不会消耗自我销毁的一贴的查看次数
+// 已经过期的自我销毁的一贴视为不存在，留给后台清理
+func ReadMeta(key string) (*Meta, error) {
+	temporary, err := IsTemporaryKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !temporary {
+		paste := Permanent{AbstractPaste: &AbstractPaste{}}
+		if err := dao.DB.Select(append(append([]string{}, metaColumns...), "delete_at")).
+			Where(map[string]interface{}{"key": key}).Take(&paste).Error; err != nil {
+			return nil, err
+		}
+		if paste.Scheduled() {
+			return nil, gorm.ErrRecordNotFound
+		}
+		meta := paste.meta(TypePermanent)
+		meta.ExpiresAt = paste.DeleteAt
+		return meta, nil
+	}
+
+	paste := Temporary{AbstractPaste: &AbstractPaste{}}
+	if err := dao.DB.Select(append(append([]string{}, metaColumns...), "expire_second", "expire_count", "expires_at")).
+		Where(map[string]interface{}{"key": key}).Take(&paste).Error; err != nil {
+		return nil, err
+	}
+	if paste.Expired() {
+		return nil, gorm.ErrRecordNotFound
+	}
+	expiresAt, remaining := paste.ExpiresAt, paste.ExpireCount
+	if expiresAt.IsZero() {
+		expiresAt = paste.deadline(paste.CreatedAt)
+	}
+	meta := paste.meta(TypeTemporary)
+	meta.ExpiresAt, meta.RemainingViews = &expiresAt, &remaining
+	return meta, nil
+}
@@ -0,0 +1,43 @@
+package paste
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestReadMeta(t *testing.T) {
+	paste := Temporary{AbstractPaste: &AbstractPaste{Lang: "go", Content: "package main", Password: "secret"}, ExpireSecond: 60, ExpireCount: 2}
+	assertNil(t, paste.Save())
+
+	for i := 0; i < 3; i++ {
+		meta, err := ReadMeta(paste.Key)
+		assertNil(t, err)
+		assertEqual(t, TypeTemporary, meta.Type)
+		assertEqual(t, "go", meta.Lang)
+		assertEqual(t, uint64(2), *meta.RemainingViews)
+		assertEqual(t, int64(len("package main")), meta.Size)
+		assertEqual(t, true, meta.HasPassword)
+		assertEqual(t, false, meta.CreatedAt.IsZero())
+		assertEqual(t, true, meta.ExpiresAt.Equal(paste.ExpiresAt))
+	}
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get("secret"))
+	meta, err := ReadMeta(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, uint64(1), *meta.RemainingViews)
+
+	permanent := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "permanent"}}
+	assertNil(t, permanent.Save())
+	meta, err = ReadMeta(permanent.Key)
+	assertNil(t, err)
+	assertEqual(t, TypePermanent, meta.Type)
+	assertEqual(t, true, meta.RemainingViews == nil)
+	assertEqual(t, true, meta.ExpiresAt == nil)
+	assertEqual(t, false, meta.HasPassword)
+
+	if _, err = ReadMeta("0notexist"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+}
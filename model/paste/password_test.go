@@ -0,0 +1,97 @@
+package paste
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/common/password"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"strings"
+	"testing"
+)
+
+func storedPaste(t *testing.T, model interface{}, key string) AbstractPaste {
+	var paste AbstractPaste
+	assertNil(t, dao.DB.Model(model).Where(map[string]interface{}{"key": key}).Take(&paste).Error)
+	return paste
+}
+
+func TestPermanentEncryption(t *testing.T) {
+	paste := Permanent{AbstractPaste: &AbstractPaste{Content: "Hello World!", Password: "secret"}}
+	assertNil(t, paste.Save())
+
+	stored := storedPaste(t, &Permanent{}, paste.Key)
+	assertEqual(t, "", stored.Password)
+	assertEqual(t, "", stored.Content)
+	assertEqual(t, encryptionPassword, stored.Encryption)
+	assertEqual(t, false, bytes.Contains(stored.EncodedContent, []byte("Hello World!")))
+
+	assertEqual(t, common.ErrWrongPassword, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Get("wrong"))
+	assertEqual(t, common.ErrWrongPassword, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Get(""))
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get("secret"))
+	assertEqual(t, "Hello World!", got.GetContent())
+}
+
+func TestTemporaryEncryption(t *testing.T) {
+	content := strings.Repeat("Hello World!\n", 100) // 同时压缩
+	paste := Temporary{AbstractPaste: &AbstractPaste{Content: content, Password: "secret"}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, paste.Save())
+
+	// 密码错误不消耗查看次数
+	assertEqual(t, common.ErrWrongPassword, (&Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Get("wrong"))
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get("secret"))
+	assertEqual(t, content, got.GetContent())
+	assertEqual(t, false, exist(paste.Key, &Temporary{}))
+}
+
+func TestMasterKeyEncryption(t *testing.T) {
+	config.Config.Encryption.MasterKey = "master key"
+	defer func() {
+		config.Config.Encryption.MasterKey = ""
+	}()
+
+	paste := Permanent{AbstractPaste: &AbstractPaste{Content: "Hello World!"}}
+	assertNil(t, paste.Save())
+	stored := storedPaste(t, &Permanent{}, paste.Key)
+	assertEqual(t, encryptionMaster, stored.Encryption)
+	assertEqual(t, "", stored.Content)
+
+	assertEqual(t, common.ErrWrongPassword, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Get("wrong"))
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "Hello World!", got.GetContent())
+}
+
+func TestLegacyPasswordUpgrade(t *testing.T) {
+	legacy := fmt.Sprintf("%x", md5.Sum([]byte("secret")))
+
+	permanent := Permanent{AbstractPaste: &AbstractPaste{Content: "Hello World!"}}
+	assertNil(t, permanent.Save())
+	assertNil(t, dao.DB.Model(&permanent).Update("password", legacy).Error)
+
+	temporary := Temporary{AbstractPaste: &AbstractPaste{Content: "Hello World!"}, ExpireSecond: 60, ExpireCount: 2}
+	assertNil(t, temporary.Save())
+	assertNil(t, dao.DB.Model(&temporary).Update("password", legacy).Error)
+
+	assertEqual(t, common.ErrWrongPassword, (&Permanent{AbstractPaste: &AbstractPaste{Key: permanent.Key}}).Get("wrong"))
+	assertEqual(t, legacy, storedPaste(t, &Permanent{}, permanent.Key).Password)
+
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: permanent.Key}}).Get("secret"))
+	assertNil(t, (&Temporary{AbstractPaste: &AbstractPaste{Key: temporary.Key}}).Get("secret"))
+
+	for _, hashed := range []string{
+		storedPaste(t, &Permanent{}, permanent.Key).Password,
+		storedPaste(t, &Temporary{}, temporary.Key).Password,
+	} {
+		assertEqual(t, false, password.IsLegacy(hashed))
+		ok, needRehash := password.Verify(hashed, "secret")
+		assertEqual(t, true, ok)
+		assertEqual(t, false, needRehash)
+	}
+}
@@ -1,10 +1,15 @@
 package paste
 
 import (
-	"crypto/md5"
+	"errors"
 	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/common/password"
 	"github.com/PasteUs/PasteMeGoBackend/handler/common"
 	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"github.com/PasteUs/PasteMeGoBackend/model/store"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -15,16 +20,50 @@ type IPaste interface {
 	GetKey() string
 	GetContent() string
 	GetLang() string
+	GetEncoding() string
+	GetEncodedContent() []byte
+	IsEncrypted() bool
+	GetIV() string
+	GetSalt() string
+	GetDeleteToken() string
+	IsBundle() bool
+	GetFiles() []File
+	GetFile(string) (File, bool)
+	IsBinary() bool
+	GetData() []byte
+	GetMimeType() string
+	GetFileName() string
+	GetParentKey() string
 }
 
 type AbstractPaste struct {
-	Key       string    `json:"key" swaggerignore:"true" gorm:"type:varchar(16);primaryKey"` // 主键:索引
-	Lang      string    `json:"lang" example:"plain" gorm:"type:varchar(16)"`                // 语言类型
-	Content   string    `json:"content" example:"Hello World!" gorm:"type:mediumtext"`       // 内容，最大长度为 16777215(2^24-1) 个字符
-	Password  string    `json:"password" example:"" gorm:"type:varchar(32)"`                 // 密码
-	ClientIP  string    `json:"client_ip" swaggerignore:"true" gorm:"type:varchar(64)"`      // 用户 IP
-	Username  string    `json:"username" swaggerignore:"true" gorm:"type:varchar(16)"`       // 用户名
-	CreatedAt time.Time `swaggerignore:"true"`                                               // 存储记录的创建时间
+	Key             string    `json:"key" swaggerignore:"true" gorm:"type:varchar(16);primaryKey"` // 主键:索引
+	Lang            string    `json:"lang" example:"plain" gorm:"type:varchar(16)"`                // 语言类型
+	Content         string    `json:"content" example:"Hello World!" gorm:"type:mediumtext"`       // 内容，最大长度为 16777215(2^24-1) 个字符
+	Password        string    `json:"password" example:"" gorm:"type:varchar(128)"`                // 密码，只有未加密的旧记录会保存哈希
+	ClientIP        string    `json:"client_ip" swaggerignore:"true" gorm:"type:varchar(64)"`      // 用户 IP
+	Username        string    `json:"username" swaggerignore:"true" gorm:"type:varchar(16)"`       // 用户名
+	CreatedAt       time.Time `swaggerignore:"true"`                                               // 存储记录的创建时间
+	ContentStore    string    `json:"-" gorm:"type:varchar(16)"`                                   // 存放内容的外部存储名称，为空时内容存放在数据库中
+	Encoding        string    `json:"-" gorm:"type:varchar(16)"`                                   // 内容的压缩方式
+	Encryption      string    `json:"-" gorm:"type:varchar(16)"`                                   // 内容的加密方式
+	EncodedContent  []byte    `json:"-" gorm:"column:compressed_content;size:16777215"`            // 压缩或加密后的内容，两者都没有时内容存放在 Content 中
+	Encrypted       bool      `json:"encrypted" example:"false"`                                   // 是否为客户端加密的内容，此时 Content 为密文
+	IV              string    `json:"iv" example:"" gorm:"type:varchar(64)"`                       // 客户端加密使用的 IV
+	Salt            string    `json:"salt" example:"" gorm:"type:varchar(64)"`                     // 客户端加密使用的盐
+	AcceptEncoding  string    `json:"-" gorm:"-"`                                                  // 读取时调用方可以直接接收的压缩方式，匹配时不解压
+	MimeType        string    `json:"-" gorm:"type:varchar(128)"`                                  // 二进制附件检测到的 MIME 类型，为空时为文本
+	FileName        string    `json:"-" gorm:"type:varchar(255)"`                                  // 二进制附件的原始文件名
+	Data            []byte    `json:"-" gorm:"-"`                                                  // 二进制附件的内容，保存时以 base64 编码到 Content 中
+	Size            int64     `json:"-"`                                                           // 原始内容的字节数，多文件时为各文件之和
+	ParentKey       string    `json:"-" gorm:"type:varchar(16);index"`                             // fork 的来源
+	Files           []File    `json:"files" gorm:"-"`                                              // 多文件 paste 的文件列表，保存时编码到 Content 中
+	DeleteTokenHash string    `json:"-" gorm:"type:varchar(64)"`                                   // 删除凭证的哈希
+	Searchable      bool      `json:"searchable" example:"false" gorm:"-"`                         // 设置了密码或自我销毁时是否仍然写入全文索引
+	storeKey        string    // 内容在外部存储中的 key，为空时使用 Key
+	deleteToken     string    // 创建时生成的删除凭证明文，只在创建的响应中返回一次
+	searchContent   string    // 写入全文索引的明文，为空时不写入
+	fetched         bool      // 外部存储中的内容已经读出
 }
 
 func (paste *AbstractPaste) GetKey() string {
@@ -39,22 +78,260 @@ func (paste *AbstractPaste) GetLang() string {
 	return paste.Lang
 }
 
-func hash(text string) string {
-	if text == "" {
-		return text
+// IsEncrypted 内容是否由客户端加密，服务端只保存密文，解密所需的密钥不会发送给服务端
+func (paste *AbstractPaste) IsEncrypted() bool {
+	return paste.Encrypted
+}
+
+func (paste *AbstractPaste) GetIV() string {
+	return paste.IV
+}
+
+func (paste *AbstractPaste) GetSalt() string {
+	return paste.Salt
+}
+
+// GetDeleteToken 返回创建时生成的删除凭证，只有刚创建的 paste 才有
+func (paste *AbstractPaste) GetDeleteToken() string {
+	return paste.deleteToken
+}
+
+// GetEncoding 返回 GetEncodedContent 的压缩方式，为空时内容已经解压到 GetContent 中
+func (paste *AbstractPaste) GetEncoding() string {
+	return paste.Encoding
+}
+
+func (paste *AbstractPaste) GetEncodedContent() []byte {
+	return paste.EncodedContent
+}
+
+// create 生成 key，按需压缩、加密内容，调用 insert 写入数据库，超过阈值时放入外部存储，key 冲突时重新生成
+// key 不为空时使用指定的 key，冲突时返回 ErrKeyConflict
+func (paste *AbstractPaste) create(key string, zeroFirst bool, insert func(tx *gorm.DB) error) error {
+	if err := paste.prepare(); err != nil {
+		return err
+	}
+	paste.searchContent = paste.searchText(zeroFirst)
+	content, secret := paste.Content, paste.Password
+	defer func() {
+		paste.Content = content
+		paste.Encoding, paste.EncodedContent = "", nil
+	}()
+
+	token, hashed, err := newDeleteToken()
+	if err != nil {
+		return err
+	}
+	paste.deleteToken, paste.DeleteTokenHash = token, hashed
+
+	if key != "" {
+		paste.Key = key
+		if err := paste.createWithKey(content, secret, insert); errors.Is(err, gorm.ErrDuplicatedKey) {
+			return common.ErrKeyConflict
+		} else {
+			return err
+		}
+	}
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		paste.Key = generator(zeroFirst)
+		err := paste.createWithKey(content, secret, insert)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		logging.Warn("paste key collided", zap.String("key", paste.Key), zap.Int("attempt", attempt))
+	}
+	return common.ErrKeyCollision
+}
+
+// prepare 多文件与二进制附件编码到 Content 中，并记录原始内容的大小
+func (paste *AbstractPaste) prepare() error {
+	switch {
+	case len(paste.Files) > 0:
+		paste.Size = 0
+		for _, file := range paste.Files {
+			paste.Size += int64(len(file.Content))
+		}
+		return paste.bundle()
+	case paste.IsBinary():
+		paste.Size = int64(len(paste.Data))
+		paste.encodeData()
+	default:
+		paste.Size = int64(len(paste.Content))
+	}
+	return nil
+}
+
+// createWithKey 使用已经设置好的 key 写入一次，key 作为加密的附加数据，每次都需要重新加密
+func (paste *AbstractPaste) createWithKey(content, secret string, insert func(tx *gorm.DB) error) error {
+	paste.Content, paste.ContentStore = content, ""
+	paste.Encoding, paste.EncodedContent = "", nil
+	paste.Password = "" // 设置了密码时内容使用密码加密，不再保存密码的哈希
+
+	if err := paste.compress(); err != nil {
+		return err
+	}
+	if err := paste.encrypt(secret); err != nil {
+		return err
+	}
+
+	var offload []byte
+	if body := paste.body(); store.Default != nil && len(body) > store.Threshold {
+		offload = body
+		paste.ContentStore = store.Default.Name()
+		paste.Content, paste.EncodedContent = "", nil
+	}
+
+	// 先写入数据库再写入外部存储，避免冲突的 key 覆盖其它 Paste 的内容
+	stored := false
+	err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := insert(tx); err != nil {
+			return err
+		}
+		if err := paste.index(tx); err != nil {
+			return err
+		}
+		if offload != nil {
+			if err := store.Default.Put(paste.contentKey(), offload); err != nil {
+				return err
+			}
+			stored = true
+		}
+		return nil
+	})
+	if err != nil && stored {
+		paste.removeContent()
+	}
+	return err
+}
+
+// contentKey 返回内容在外部存储中的 key
+func (paste *AbstractPaste) contentKey() string {
+	if paste.storeKey != "" {
+		return paste.storeKey
+	}
+	return paste.Key
+}
+
+func (paste *AbstractPaste) encoded() bool {
+	return paste.Encoding != "" || paste.Encryption != ""
+}
+
+// body 返回实际需要存储的内容
+func (paste *AbstractPaste) body() []byte {
+	if paste.encoded() {
+		return paste.EncodedContent
+	}
+	return []byte(paste.Content)
+}
+
+// open 校验密码并读出解密后的内容，密码错误时返回 common.ErrWrongPassword
+// upgraded 表示旧记录的密码哈希已经更新，需要写回数据库
+func (paste *AbstractPaste) open(password string) (upgraded bool, err error) {
+	if upgraded, err = paste.verify(password); err != nil {
+		return false, err
+	}
+	if err = paste.fetch(); err != nil {
+		return false, err
 	}
-	return fmt.Sprintf("%x", md5.Sum([]byte(text)))
+	return upgraded, paste.decrypt(password)
 }
 
-func (paste *AbstractPaste) checkPassword(password string) *common.ErrorResponse {
-	if paste.Password == hash(password) {
+// verify 校验旧记录的密码哈希，使用密码加密的内容在解密时校验
+func (paste *AbstractPaste) verify(password string) (upgraded bool, err error) {
+	if paste.Encryption == encryptionPassword {
+		return false, nil
+	}
+	return paste.checkPassword(password)
+}
+
+// fetch 从外部存储中读出内容
+func (paste *AbstractPaste) fetch() error {
+	if paste.ContentStore == "" || paste.fetched {
 		return nil
 	}
-	return common.ErrWrongPassword
+	contentStore := store.Get(paste.ContentStore)
+	if contentStore == nil {
+		return fmt.Errorf("content store %s not configured", paste.ContentStore)
+	}
+	body, err := contentStore.Get(paste.contentKey())
+	if err != nil {
+		return err
+	}
+	if paste.encoded() {
+		paste.EncodedContent = body
+	} else {
+		paste.Content = string(body)
+	}
+	paste.fetched = true
+	return nil
+}
+
+// decode 在调用方不能直接接收压缩后的内容时解压，客户端加密的密文需要放进 JSON 中返回，多文件和二进制附件需要还原，总是解压
+func (paste *AbstractPaste) decode() error {
+	if paste.Encoding != "" && (paste.Encoding != paste.AcceptEncoding || paste.Encrypted || paste.IsBundle() || paste.IsBinary()) {
+		if err := paste.decompress(); err != nil {
+			return err
+		}
+	}
+	if err := paste.decodeData(); err != nil {
+		return err
+	}
+	return paste.unbundle()
+}
+
+// detach 删除 paste 时一并删除全文索引、标签、合集中的成员以及查看统计
+func detach(tx *gorm.DB, keys ...string) error {
+	if err := unindex(tx, keys...); err != nil {
+		return err
+	}
+	for _, model := range []interface{}{&PasteTag{}, &CollectionItem{}, &PasteView{}, &PasteViewSummary{}, &ExpiryExtension{}} {
+		if err := tx.Where(map[string]interface{}{"key": keys}).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeContent 删除外部存储中的内容，失败时只记录日志
+func (paste *AbstractPaste) removeContent() {
+	if paste.ContentStore == "" {
+		return
+	}
+	removeContent(paste.ContentStore, paste.contentKey())
+}
+
+func removeContent(name string, key string) {
+	contentStore := store.Get(name)
+	if contentStore == nil {
+		logging.Warn("content store not configured", zap.String("content_store", name), zap.String("key", key))
+		return
+	}
+	if err := contentStore.Delete(key); err != nil {
+		logging.Error("delete content failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// checkPassword 校验密码，旧的 MD5 哈希校验通过后会替换为新的哈希，upgraded 表示 Password 需要写回数据库
+func (paste *AbstractPaste) checkPassword(text string) (upgraded bool, err error) {
+	ok, needRehash := password.Verify(paste.Password, text)
+	if !ok {
+		return false, common.ErrWrongPassword
+	}
+	if !needRehash {
+		return false, nil
+	}
+	hashed, e := password.Hash(text)
+	if e != nil {
+		logging.Warn("rehash password failed", zap.String("key", paste.Key), zap.Error(e))
+		return false, nil
+	}
+	paste.Password = hashed
+	return true, nil
 }
 
 func exist(key string, model interface{}) bool {
 	count := int64(0)
-	dao.DB.Model(model).Where("`key` = ?", key).Count(&count)
+	dao.DB.Model(model).Where(map[string]interface{}{"key": key}).Count(&count)
 	return count > 0
 }
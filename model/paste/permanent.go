@@ -1,40 +1,134 @@
 package paste
 
 import (
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
 	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
-func init() {
-	dao.CreateTable(&Permanent{})
-}
-
 // Permanent 永久
 type Permanent struct {
 	*AbstractPaste
+	Revision  uint       `json:"revision" swaggerignore:"true" gorm:"default:1"` // 当前的修订号，每次修改加一
+	UpdatedAt time.Time  `swaggerignore:"true"`                                  // 当前版本的创建时间
+	DeleteAt  *time.Time `json:"delete_at,omitempty" gorm:"index"`               // 创建者设置的删除时间，到期后由后台移入回收站
 	// 存储记录的删除时间
 	// 删除具有 DeletedAt 字段的记录，它不会从数据库中删除，但只将字段 DeletedAt 设置为当前时间，并在查询时无法找到记录
 	DeletedAt gorm.DeletedAt
 }
 
-// Save 成员函数，创建
+// Save 成员函数，创建，Key 不为空时使用自定义的 key
 func (paste *Permanent) Save() error {
-	paste.Key = generator(8, false, &paste)
-	paste.Password = hash(paste.Password)
-	return dao.DB.Create(&paste).Error
+	paste.Revision = 1
+	return paste.create(paste.Key, false, func(tx *gorm.DB) error {
+		return tx.Create(&paste).Error
+	})
+}
+
+// Edit 成员函数，以 revision 版本为基础修改内容，修改前的版本保存为一条 PasteRevision
+// 只有创建者可以修改，revision 不是当前版本时返回 common.ErrRevisionMismatch
+func (paste *Permanent) Edit(username string, revision uint) error {
+	if err := paste.prepare(); err != nil {
+		return err
+	}
+	paste.searchContent = paste.searchText(false)
+	content, secret := paste.Content, paste.Password
+	defer func() {
+		paste.Content = content
+		paste.Encoding, paste.EncodedContent = "", nil
+		readCache.invalidate(paste.Key) // 事务结束后失效，之前开始的读取不会再放入旧内容
+	}()
+
+	return paste.createWithKey(content, secret, func(tx *gorm.DB) error {
+		head := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&head).Error; err != nil {
+			return err
+		}
+		if head.Username == "" || head.Username != username {
+			return common.ErrNotOwner
+		}
+		if head.Revision != revision {
+			return common.ErrRevisionMismatch
+		}
+
+		snapshot := PasteRevision{AbstractPaste: head.AbstractPaste, Revision: head.Revision}
+		snapshot.CreatedAt = head.revisedAt()
+		if err := tx.Create(&snapshot).Error; err != nil {
+			return err
+		}
+
+		paste.Revision, paste.UpdatedAt = head.Revision+1, time.Now()
+		paste.Username, paste.ClientIP, paste.CreatedAt = head.Username, head.ClientIP, head.CreatedAt
+		paste.storeKey = revisionKey(paste.Key, paste.Revision) // 先于写入外部存储设置，不会覆盖旧版本的内容
+		return tx.Model(&head).
+			Select("lang", "content", "password", "content_store", "encoding", "encryption",
+				"compressed_content", "encrypted", "iv", "salt", "mime_type", "file_name", "size",
+				"revision", "updated_at").
+			Updates(paste).Error
+	})
 }
 
 // Delete 成员函数，删除
 func (paste *Permanent) Delete() error {
-	return dao.DB.Delete(&paste).Error
+	defer readCache.invalidate(paste.Key)
+	return dao.DB.Delete(&paste).Error // 软删除，全文索引保留到彻底删除时，搜索时过滤回收站中的 paste
 }
 
+// Get 成员函数，读取，命中读缓存时不访问数据库和外部存储，密码校验与解密仍然每次执行
 func (paste *Permanent) Get(password string) error {
-	if err := dao.DB.Take(&paste).Error; err != nil {
+	cached, epoch := readCache.get(paste.Key)
+	if cached != nil {
+		acceptEncoding := paste.AcceptEncoding
+		*paste = *cached
+		paste.AcceptEncoding = acceptEncoding
+	} else {
+		if err := dao.DB.Take(&paste).Error; err != nil {
+			return err
+		}
+		paste.storeKey = revisionKey(paste.Key, paste.Revision)
+	}
+	if paste.Scheduled() {
+		return gorm.ErrRecordNotFound // 已经到了删除时间，等待后台移入回收站
+	}
+
+	upgraded, err := paste.verify(password)
+	if err != nil {
+		return err
+	}
+	if err = paste.fetch(); err != nil {
 		return err
 	}
-	if err := paste.checkPassword(password); err != nil {
+	if upgraded {
+		if e := dao.DB.Model(&paste).Update("password", paste.Password).Error; e != nil {
+			logging.Warn("save upgraded password failed", zap.String("key", paste.Key), zap.Error(e))
+		}
+		readCache.invalidate(paste.Key)
+	} else if cached == nil {
+		readCache.put(paste, epoch) // 放入的是解密前的内容
+	}
+	if err = paste.decrypt(password); err != nil {
 		return err
 	}
-	return nil
+	return paste.decode()
+}
+
+// Scheduled 是否已经到了创建者设置的删除时间
+func (paste *Permanent) Scheduled() bool {
+	return paste.DeleteAt != nil && !paste.DeleteAt.After(time.Now())
+}
+
+func (paste *Permanent) GetRevision() uint {
+	return paste.Revision
+}
+
+// revisedAt 当前版本的创建时间，旧记录没有 UpdatedAt 时使用 CreatedAt
+func (paste *Permanent) revisedAt() time.Time {
+	if paste.UpdatedAt.IsZero() {
+		return paste.CreatedAt
+	}
+	return paste.UpdatedAt
 }
@@ -0,0 +1,93 @@
+package paste
+
+import (
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"go.uber.org/zap"
+	"time"
+)
+
+// PasteRevision 修订记录，保存 Permanent 被修改前的版本，CreatedAt 为该版本的创建时间
+type PasteRevision struct {
+	*AbstractPaste
+	Revision uint `json:"revision" gorm:"primaryKey;autoIncrement:false"` // 修订号，从 1 开始
+}
+
+// RevisionInfo 修订的元信息，不包含内容
+type RevisionInfo struct {
+	Revision  uint      `json:"revision" example:"1"`
+	Lang      string    `json:"lang" example:"plain"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// revisionKey 修改后的版本在外部存储中使用带修订号的 key，"_" 不在 key 的字符集中，不会与其它 Paste 冲突
+func revisionKey(key string, revision uint) string {
+	if revision <= 1 {
+		return key
+	}
+	return fmt.Sprintf("%s_%d", key, revision)
+}
+
+// Save 成员函数，保存修订记录，内容需要已经压缩、加密
+func (paste *PasteRevision) Save() error {
+	return dao.DB.Create(&paste).Error
+}
+
+// Delete 成员函数，删除修订记录及其在外部存储中的内容
+func (paste *PasteRevision) Delete() error {
+	if err := dao.DB.Delete(&paste).Error; err != nil {
+		return err
+	}
+	paste.storeKey = revisionKey(paste.Key, paste.Revision)
+	paste.removeContent()
+	return nil
+}
+
+// Get 成员函数，读取指定的版本，Revision 为当前版本时读取 Permanent
+func (paste *PasteRevision) Get(password string) error {
+	head := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, AcceptEncoding: paste.AcceptEncoding}}
+	if err := dao.DB.Select("key", "revision").Take(&head).Error; err != nil {
+		return err // Permanent 已删除时它的历史版本也不再可读
+	}
+	if head.Revision == paste.Revision {
+		if err := head.Get(password); err != nil {
+			return err
+		}
+		*paste.AbstractPaste = *head.AbstractPaste
+		paste.CreatedAt = head.revisedAt()
+		return nil
+	}
+
+	if err := dao.DB.Take(&paste).Error; err != nil {
+		return err
+	}
+	paste.storeKey = revisionKey(paste.Key, paste.Revision)
+	upgraded, err := paste.open(password)
+	if err != nil {
+		return err
+	}
+	if upgraded {
+		if e := dao.DB.Model(&paste).Update("password", paste.Password).Error; e != nil {
+			logging.Warn("save upgraded password failed", zap.String("key", paste.Key), zap.Int("revision", int(paste.Revision)), zap.Error(e))
+		}
+	}
+	return paste.decode()
+}
+
+func (paste *PasteRevision) GetRevision() uint {
+	return paste.Revision
+}
+
+// Revisions 成员函数，按修订号从小到大列出全部版本，最后一项为当前版本
+func (paste *Permanent) Revisions() ([]RevisionInfo, error) {
+	if err := dao.DB.Select("key", "lang", "revision", "created_at", "updated_at").Take(&paste).Error; err != nil {
+		return nil, err
+	}
+	var revisions []RevisionInfo
+	if err := dao.DB.Model(&PasteRevision{}).Select("revision", "lang", "created_at").
+		Where(map[string]interface{}{"key": paste.Key}).Order("revision").Scan(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return append(revisions, RevisionInfo{Revision: paste.Revision, Lang: paste.Lang, CreatedAt: paste.revisedAt()}), nil
+}
@@ -0,0 +1,89 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"testing"
+)
+
+func editedPermanent(t *testing.T, key string, username string, content string) *Permanent {
+	paste := Permanent{AbstractPaste: &AbstractPaste{Key: key, Lang: "plain", Content: content, Username: username}}
+	assertNil(t, paste.Save())
+	t.Cleanup(func() {
+		assertNil(t, dao.DB.Unscoped().Delete(&paste).Error)
+		assertNil(t, dao.DB.Where(map[string]interface{}{"key": key}).Delete(&PasteRevision{}).Error)
+	})
+	return &paste
+}
+
+func readRevision(t *testing.T, key string, revision uint, password string) *PasteRevision {
+	paste := PasteRevision{AbstractPaste: &AbstractPaste{Key: key}, Revision: revision}
+	assertNil(t, paste.Get(password))
+	return &paste
+}
+
+func TestPermanentEdit(t *testing.T) {
+	paste := editedPermanent(t, "runbook", "alice", "version one")
+	assertEqual(t, uint(1), paste.Revision)
+
+	edit := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, Lang: "bash", Content: "version two"}}
+	if err := edit.Edit("bob", 1); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	assertNil(t, edit.Edit("alice", 1))
+	assertEqual(t, uint(2), edit.Revision)
+	assertEqual(t, "version two", edit.Content)
+
+	// 基于旧版本的修改需要先获取最新版本
+	stale := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, Lang: "plain", Content: "stale"}}
+	if err := stale.Edit("alice", 1); !errors.Is(err, common.ErrRevisionMismatch) {
+		t.Fatalf("expected revision mismatch, got %v", err)
+	}
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "version two", got.Content)
+	assertEqual(t, "bash", got.Lang)
+	assertEqual(t, uint(2), got.Revision)
+	assertEqual(t, "alice", got.Username)
+
+	assertEqual(t, "version one", readRevision(t, paste.Key, 1, "").Content)
+	assertEqual(t, "version two", readRevision(t, paste.Key, 2, "").Content)
+
+	missing := PasteRevision{AbstractPaste: &AbstractPaste{Key: paste.Key}, Revision: 3}
+	assertEqual(t, true, missing.Get("") != nil)
+
+	revisions, err := (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Revisions()
+	assertNil(t, err)
+	assertEqual(t, 2, len(revisions))
+	assertEqual(t, uint(1), revisions[0].Revision)
+	assertEqual(t, "plain", revisions[0].Lang)
+	assertEqual(t, uint(2), revisions[1].Revision)
+	assertEqual(t, "bash", revisions[1].Lang)
+}
+
+func TestPermanentEditWithoutOwner(t *testing.T) {
+	paste := editedPermanent(t, "anonymous", "", "content")
+	edit := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, Lang: "plain", Content: "changed"}}
+	if err := edit.Edit("", 1); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+}
+
+func TestPermanentEditContentStore(t *testing.T) {
+	useFilesystemStore(t)
+	paste := editedPermanent(t, "stored-runbook", "alice", "stored version one")
+
+	edit := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, Lang: "plain", Content: "stored version two", Password: "secret"}}
+	assertNil(t, edit.Edit("alice", 1))
+
+	// 新版本写入带修订号的 key，不会覆盖旧版本的内容
+	assertEqual(t, "stored version one", readRevision(t, paste.Key, 1, "").Content)
+	assertEqual(t, "stored version two", readRevision(t, paste.Key, 2, "secret").Content)
+
+	wrong := PasteRevision{AbstractPaste: &AbstractPaste{Key: paste.Key}, Revision: 2}
+	if err := wrong.Get(""); !errors.Is(err, common.ErrWrongPassword) {
+		t.Fatalf("expected wrong password, got %v", err)
+	}
+}
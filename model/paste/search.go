@@ -0,0 +1,218 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+
+	snippetLength  = 160 // 片段的最大字符数
+	snippetContext = 40  // 片段中第一处匹配之前保留的字符数
+)
+
+// PasteSearch 全文索引中的一贴，保存明文，只有允许搜索的 paste 会写入
+// 设置了密码和自我销毁的 paste 需要创建时指定 Searchable 才会写入，客户端加密与二进制附件总是不写入
+type PasteSearch struct {
+	Key      string `gorm:"type:varchar(16);primaryKey"`
+	Username string `gorm:"type:varchar(16);index"`
+	Lang     string `gorm:"type:varchar(16)"`
+	Content  string `gorm:"type:mediumtext"`
+}
+
+// SearchResult 搜索结果，Snippet 为匹配附近的一段内容
+type SearchResult struct {
+	Key        string   `json:"key" example:"a1b2c3d4"`
+	Type       string   `json:"type" example:"permanent"`
+	Lang       string   `json:"lang" example:"plain"`
+	Snippet    string   `json:"snippet" example:"server_name example.com;"`
+	Highlights [][2]int `json:"highlights"` // 片段中匹配的位置，按字符计算的 [起始, 结束)
+}
+
+// searchText 返回写入全文索引的内容，不允许搜索时为空
+// 在 prepare 之后、压缩加密之前调用，此时 Content 与 Password 仍为明文
+func (paste *AbstractPaste) searchText(temporary bool) string {
+	if paste.Encrypted || paste.IsBinary() {
+		return ""
+	}
+	if (temporary || paste.Password != "") && !paste.Searchable {
+		return ""
+	}
+	if len(paste.Files) == 0 {
+		return paste.Content
+	}
+	texts := make([]string, 0, len(paste.Files)*2)
+	for _, file := range paste.Files {
+		texts = append(texts, file.Name, file.Content)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// index 在写入 paste 的事务中更新全文索引，匿名的 paste 无法按用户搜索，不写入
+func (paste *AbstractPaste) index(tx *gorm.DB) error {
+	if err := unindex(tx, paste.Key); err != nil {
+		return err
+	}
+	if paste.searchContent == "" || paste.Username == "" {
+		return nil
+	}
+	return tx.Create(&PasteSearch{
+		Key: paste.Key, Username: paste.Username, Lang: paste.Lang, Content: paste.searchContent,
+	}).Error
+}
+
+// unindex 从全文索引中删除
+func unindex(tx *gorm.DB, keys ...string) error {
+	return tx.Where(map[string]interface{}{"key": keys}).Delete(&PasteSearch{}).Error
+}
+
+// Search 在用户的 paste 中搜索包含全部关键词的一贴，按相关度排列
+// 回收站中的和已经过期的 paste 不会出现在结果中，搜索不会消耗自我销毁的一贴的查看次数
+func Search(username string, query string, limit int) ([]SearchResult, error) {
+	results := make([]SearchResult, 0)
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+	if limit <= 0 || limit > MaxSearchLimit {
+		limit = DefaultSearchLimit
+	}
+
+	permanent := dao.DB.Model(&Permanent{}).Select("key").
+		Where(map[string]interface{}{"username": username}).Where("deleted_at IS NULL").
+		Where("delete_at IS NULL OR delete_at > ?", time.Now())
+	temporary := dao.DB.Model(&Temporary{}).Select("key").
+		Where(map[string]interface{}{"username": username}).
+		Where("expires_at > ? AND expire_count > 0", time.Now())
+	// 类型由同一个子查询得出，不需要再逐条查询
+	db := dao.DB.Model(&PasteSearch{}).
+		Select("paste_search.key, paste_search.lang, paste_search.content, "+
+			"CASE WHEN paste_search.key IN (?) THEN ? ELSE ? END AS type", temporary, TypeTemporary, TypePermanent).
+		Where("paste_search.username = ?", username).
+		Where("paste_search.key IN (?) OR paste_search.key IN (?)", permanent, temporary)
+
+	switch dao.DB.Dialector.Name() {
+	case "mysql":
+		against := booleanQuery(terms)
+		db = db.Where("MATCH (paste_search.content) AGAINST (? IN BOOLEAN MODE)", against).
+			Order(clause.Expr{SQL: "MATCH (paste_search.content) AGAINST (? IN BOOLEAN MODE) DESC", Vars: []interface{}{against}})
+	case "postgres":
+		db = db.Where("to_tsvector('simple', paste_search.content) @@ plainto_tsquery('simple', ?)", query).
+			Order(clause.Expr{
+				SQL:  "ts_rank(to_tsvector('simple', paste_search.content), plainto_tsquery('simple', ?)) DESC",
+				Vars: []interface{}{query},
+			})
+	default:
+		if dao.DB.Migrator().HasTable("paste_search_fts") {
+			db = db.Joins("JOIN paste_search_fts ON paste_search_fts.rowid = paste_search.rowid").
+				Where("paste_search_fts MATCH ?", phraseQuery(terms)).Order("paste_search_fts.rank")
+		} else {
+			// 没有 FTS5 时退化为 LIKE，SQLite 的 LIKE 对 ASCII 不区分大小写
+			for _, term := range terms {
+				db = db.Where("paste_search.content LIKE ? ESCAPE '\\'", "%"+escapeLike(term)+"%")
+			}
+			db = db.Order("paste_search.key")
+		}
+	}
+
+	var rows []struct {
+		Key     string
+		Lang    string
+		Content string
+		Type    string
+	}
+	if err := db.Limit(limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result := SearchResult{Key: row.Key, Type: row.Type, Lang: row.Lang}
+		result.Snippet, result.Highlights = snippet(row.Content, terms)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// phraseQuery 每个关键词作为一个 FTS5 短语，避免其中的运算符被解析
+func phraseQuery(terms []string) string {
+	phrases := make([]string, 0, len(terms))
+	for _, term := range terms {
+		phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(phrases, " ")
+}
+
+// booleanQuery 每个关键词都必须出现的 MySQL BOOLEAN MODE 查询
+func booleanQuery(terms []string) string {
+	phrases := make([]string, 0, len(terms))
+	for _, term := range terms {
+		phrases = append(phrases, `+"`+strings.ReplaceAll(term, `"`, "")+`"`)
+	}
+	return strings.Join(phrases, " ")
+}
+
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
+func lowerRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+func hasPrefix(runes []rune, prefix []rune) bool {
+	if len(prefix) > len(runes) {
+		return false
+	}
+	for i, r := range prefix {
+		if runes[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// snippet 截取第一处匹配附近的内容，返回片段与其中全部匹配的位置，匹配时不区分大小写
+func snippet(content string, terms []string) (string, [][2]int) {
+	runes, lower := []rune(content), lowerRunes(content)
+	patterns := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		patterns = append(patterns, lowerRunes(term))
+	}
+	match := func(i int) int {
+		for _, pattern := range patterns {
+			if hasPrefix(lower[i:], pattern) {
+				return len(pattern)
+			}
+		}
+		return 0
+	}
+
+	start := 0
+	for i := range lower {
+		if match(i) > 0 {
+			start = max(0, i-snippetContext)
+			break
+		}
+	}
+	end := min(len(runes), start+snippetLength)
+
+	highlights := make([][2]int, 0)
+	for i := start; i < end; {
+		if n := match(i); n > 0 && i+n <= end {
+			highlights = append(highlights, [2]int{i - start, i + n - start})
+			i += n
+		} else {
+			i++
+		}
+	}
+	return string(runes[start:end]), highlights
+}
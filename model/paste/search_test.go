@@ -0,0 +1,110 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"testing"
+)
+
+func searchKeys(t *testing.T, username string, query string) []string {
+	results, err := Search(username, query, 0)
+	assertNil(t, err)
+	keys := make([]string, 0, len(results))
+	for _, result := range results {
+		keys = append(keys, result.Key)
+	}
+	return keys
+}
+
+func TestSearch(t *testing.T) {
+	const username = "searcher"
+	nginx := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Username: username,
+		Content: "server {\n    listen 80;\n    server_name example.com;\n}"}}
+	assertNil(t, nginx.Save())
+	locked := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Username: username,
+		Content: "nginx server with password", Password: "secret"}}
+	assertNil(t, locked.Save())
+	temporary := Temporary{AbstractPaste: &AbstractPaste{Lang: "plain", Username: username,
+		Content: "temporary server notes"}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, temporary.Save())
+	optIn := Temporary{AbstractPaste: &AbstractPaste{Lang: "plain", Username: username,
+		Content: "searchable server notes", Searchable: true}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, optIn.Save())
+	other := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Username: "someone", Content: "server"}}
+	assertNil(t, other.Save())
+
+	results, err := Search(username, "SERVER", 0)
+	assertNil(t, err)
+	assertEqual(t, 2, len(results))
+	for _, result := range results {
+		switch result.Key {
+		case nginx.Key:
+			assertEqual(t, TypePermanent, result.Type)
+		case optIn.Key:
+			assertEqual(t, TypeTemporary, result.Type)
+		default:
+			t.Fatalf("unexpected key %s", result.Key)
+		}
+	}
+	assertEqual(t, 0, len(searchKeys(t, username, "")))
+
+	results, err = Search(username, "server_name example", 0)
+	assertNil(t, err)
+	assertEqual(t, 1, len(results))
+	assertEqual(t, nginx.Key, results[0].Key)
+	assertEqual(t, TypePermanent, results[0].Type)
+	assertEqual(t, 2, len(results[0].Highlights))
+	highlight := []rune(results[0].Snippet)[results[0].Highlights[0][0]:results[0].Highlights[0][1]]
+	assertEqual(t, "server_name", string(highlight))
+
+	// 搜索不消耗查看次数，读取后最后一次查看删除了索引
+	stored := Temporary{}
+	assertNil(t, dao.DB.Where(map[string]interface{}{"key": optIn.Key}).Take(&stored).Error)
+	assertEqual(t, uint64(1), stored.ExpireCount)
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: optIn.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, 0, len(searchKeys(t, username, "searchable")))
+
+	// 编辑后重新索引，移入回收站后不再出现
+	edit := Permanent{AbstractPaste: &AbstractPaste{Key: nginx.Key, Lang: "plain", Content: "upstream backend {}"}}
+	assertNil(t, edit.Edit(username, 1))
+	assertEqual(t, 0, len(searchKeys(t, username, "server_name")))
+	assertEqual(t, 1, len(searchKeys(t, username, "upstream")))
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: nginx.Key}}).Remove(username, false))
+	assertEqual(t, 0, len(searchKeys(t, username, "upstream")))
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: nginx.Key}}).Restore(username, false))
+	assertEqual(t, 1, len(searchKeys(t, username, "upstream")))
+}
+
+func TestSearchDeleteRestore(t *testing.T) {
+	const username = "trash-searcher"
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Username: username, Content: "restorable runbook"}}
+	assertNil(t, paste.Save())
+	assertEqual(t, 1, len(searchKeys(t, username, "runbook")))
+
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Remove(username, false))
+	assertEqual(t, 0, len(searchKeys(t, username, "runbook")))
+
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Restore(username, false))
+	keys := searchKeys(t, username, "runbook")
+	assertEqual(t, 1, len(keys))
+	assertEqual(t, paste.Key, keys[0])
+}
+
+func TestSnippet(t *testing.T) {
+	content := "Ünïcode prefix and the MATCH is here, then match again"
+	text, highlights := snippet(content, []string{"match"})
+	assertEqual(t, content, text)
+	assertEqual(t, 2, len(highlights))
+	runes := []rune(text)
+	assertEqual(t, "MATCH", string(runes[highlights[0][0]:highlights[0][1]]))
+	assertEqual(t, "match", string(runes[highlights[1][0]:highlights[1][1]]))
+
+	long := make([]rune, 0, 500)
+	for i := 0; i < 300; i++ {
+		long = append(long, 'x')
+	}
+	long = append(long, []rune("needle")...)
+	text, highlights = snippet(string(long), []string{"needle"})
+	assertEqual(t, snippetContext+len("needle"), len([]rune(text)))
+	assertEqual(t, [2]int{snippetContext, snippetContext + len("needle")}, highlights[0])
+}
@@ -0,0 +1,150 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// sweeper 后台定期分批删除已过期的 Temporary，将到了删除时间的 Permanent 移入回收站，并彻底删除回收站中超过保留时间的 Permanent
+type sweeper struct {
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+var (
+	runningSweeper *sweeper
+	sweeperMutex   sync.Mutex
+)
+
+// StartSweeper 启动后台清理协程，重复调用时不会启动多个
+func StartSweeper(interval time.Duration, batchSize int) {
+	sweeperMutex.Lock()
+	defer sweeperMutex.Unlock()
+
+	if runningSweeper != nil {
+		return
+	}
+	runningSweeper = &sweeper{
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go runningSweeper.run()
+	logging.Info("sweeper started", zap.Duration("interval", interval), zap.Int("batch_size", batchSize))
+}
+
+// StopSweeper 停止后台清理协程，并等待正在进行的清理结束
+func StopSweeper() {
+	sweeperMutex.Lock()
+	defer sweeperMutex.Unlock()
+
+	if runningSweeper == nil {
+		return
+	}
+	close(runningSweeper.stop)
+	<-runningSweeper.done
+	runningSweeper = nil
+	logging.Info("sweeper stopped")
+}
+
+func (s *sweeper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sweepAll()
+		}
+	}
+}
+
+// sweepAll 删除已过期的 Temporary，将到了删除时间的永久 paste 移入回收站，再彻底删除超过保留时间的永久 paste
+func (s *sweeper) sweepAll() {
+	s.drain("expired pastes deleted", "sweep expired pastes failed", sweep)
+	s.drain("scheduled pastes deleted", "delete scheduled pastes failed", retire)
+	if config.Config.Trash.RetentionDays > 0 {
+		s.drain("deleted pastes purged", "purge deleted pastes failed", func(batchSize int) (int64, error) {
+			return purge(time.Now().Add(-retention()), batchSize)
+		})
+	}
+}
+
+// drain 一直分批删除，直到没有需要删除的记录或者收到停止信号
+func (s *sweeper) drain(message string, failedMessage string, batch func(batchSize int) (int64, error)) {
+	for {
+		count, err := batch(s.batchSize)
+		if err != nil {
+			logging.Error(failedMessage, zap.Error(err))
+			return
+		}
+		if count > 0 {
+			logging.Info(message, zap.Int64("count", count))
+		}
+		if count < int64(s.batchSize) {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+}
+
+// sweep 删除至多 batchSize 条已过期的 Temporary，返回删除的条数
+func sweep(batchSize int) (int64, error) {
+	var expired []AbstractPaste
+	if err := dao.DB.Model(&Temporary{}).Select("key", "content_store").Where("expires_at <= ?", time.Now()).
+		Limit(batchSize).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, 0, len(expired))
+	for _, paste := range expired {
+		keys = append(keys, paste.Key)
+	}
+	result := dao.DB.Where(map[string]interface{}{"key": keys}).Delete(&Temporary{AbstractPaste: &AbstractPaste{}})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := detach(dao.DB, keys...); err != nil {
+		return 0, err
+	}
+	for _, paste := range expired {
+		paste.removeContent()
+	}
+	return result.RowsAffected, nil
+}
+
+// retire 将至多 batchSize 条到了删除时间的永久 paste 移入回收站，返回移入的条数
+func retire(batchSize int) (int64, error) {
+	var keys []string
+	if err := dao.DB.Model(&Permanent{}).Where("delete_at <= ?", time.Now()).
+		Limit(batchSize).Pluck("key", &keys).Error; err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	result := dao.DB.Where(map[string]interface{}{"key": keys}).Delete(&Permanent{AbstractPaste: &AbstractPaste{}})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	readCache.invalidate(keys...)
+	return result.RowsAffected, nil
+}
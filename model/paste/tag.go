@@ -0,0 +1,147 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MaxTags      = 20 // 每一贴的标签数量上限
+	maxTagLength = 32
+)
+
+var tagPattern = regexp.MustCompile(`^[0-9a-z\p{Han}][0-9a-z\p{Han}._-]*$`)
+
+// PasteTag 用户给自己的 paste 打上的标签
+type PasteTag struct {
+	Key      string `gorm:"type:varchar(16);primaryKey"`
+	Name     string `gorm:"type:varchar(32);primaryKey"`
+	Username string `gorm:"type:varchar(16);index"`
+}
+
+// TagInfo 用户的一个标签以及使用它的 paste 数量
+type TagInfo struct {
+	Name  string `json:"name" example:"incident-42"`
+	Count int64  `json:"count" example:"3"`
+}
+
+// normalizeTags 转为小写并去重，不合法时返回 common.ErrInvalidTag
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > MaxTags {
+		return nil, common.ErrTooManyTags
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len([]rune(tag)) > maxTagLength || !tagPattern.MatchString(tag) {
+			return nil, common.ErrInvalidTag
+		}
+		if !contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func contains(slice []string, value string) bool {
+	for _, item := range slice {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// owner 返回 paste 的创建者，回收站中和已经过期的 paste 视为不存在
+func owner(tx *gorm.DB, key string) (string, error) {
+	var paste AbstractPaste
+	var model interface{} = &Permanent{}
+	if temporary, err := isTemporaryKey(tx, key); err != nil {
+		return "", err
+	} else if temporary {
+		model = &Temporary{}
+	}
+	query := tx.Model(model).Select("username").Where(map[string]interface{}{"key": key})
+	if _, ok := model.(*Temporary); ok {
+		query = query.Where("expires_at > ? AND expire_count > 0", time.Now())
+	}
+	if err := query.Take(&paste).Error; err != nil {
+		return "", err
+	}
+	return paste.Username, nil
+}
+
+// SetTags 替换 paste 的全部标签，只有创建者可以修改，返回整理后的标签
+func SetTags(key string, username string, tags []string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, dao.DB.Transaction(func(tx *gorm.DB) error {
+		creator, err := owner(tx, key)
+		if err != nil {
+			return err
+		}
+		if creator == "" || creator != username {
+			return common.ErrNotOwner
+		}
+		if err = tx.Where(map[string]interface{}{"key": key}).Delete(&PasteTag{}).Error; err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		rows := make([]PasteTag, 0, len(tags))
+		for _, tag := range tags {
+			rows = append(rows, PasteTag{Key: key, Name: tag, Username: username})
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// GetTags 返回用户给 paste 打上的标签，按名称排列
+func GetTags(key string, username string) ([]string, error) {
+	tags := make([]string, 0)
+	if err := dao.DB.Model(&PasteTag{}).Where(map[string]interface{}{"key": key, "username": username}).
+		Order("name").Pluck("name", &tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Tags 列出用户的全部标签，按名称排列
+func Tags(username string) ([]TagInfo, error) {
+	tags := make([]TagInfo, 0)
+	if err := dao.DB.Model(&PasteTag{}).Select("name", "COUNT(*) AS count").
+		Where(map[string]interface{}{"username": username}).
+		Group("name").Order("name").Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// DeleteTag 从用户的全部 paste 上移除标签，返回移除的数量
+func DeleteTag(username string, name string) (int64, error) {
+	result := dao.DB.Where(map[string]interface{}{"username": username, "name": strings.ToLower(name)}).Delete(&PasteTag{})
+	return result.RowsAffected, result.Error
+}
+
+// tagsOf 批量读取 paste 的标签
+func tagsOf(username string, keys []string) (map[string][]string, error) {
+	var rows []PasteTag
+	if err := dao.DB.Where(map[string]interface{}{"username": username, "key": keys}).
+		Order("name").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	tags := make(map[string][]string, len(rows))
+	for _, row := range rows {
+		tags[row.Key] = append(tags[row.Key], row.Name)
+	}
+	return tags, nil
+}
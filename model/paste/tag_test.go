@@ -0,0 +1,60 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	const username = "tagger"
+	nginx := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "nginx", Username: username}}
+	assertNil(t, nginx.Save())
+	redis := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "redis", Username: username}}
+	assertNil(t, redis.Save())
+
+	tags, err := SetTags(nginx.Key, username, []string{"Incident-42", "web", "incident-42"})
+	assertNil(t, err)
+	assertEqual(t, 2, len(tags))
+	assertEqual(t, "incident-42", tags[0])
+	_, err = SetTags(redis.Key, username, []string{"incident-42"})
+	assertNil(t, err)
+
+	if _, err = SetTags(nginx.Key, "someone", []string{"mine"}); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	if _, err = SetTags(nginx.Key, username, []string{"has space"}); !errors.Is(err, common.ErrInvalidTag) {
+		t.Fatalf("expected invalid tag, got %v", err)
+	}
+	if _, err = SetTags("notexist", username, []string{"web"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+
+	infos, err := Tags(username)
+	assertNil(t, err)
+	assertEqual(t, 2, len(infos))
+	assertEqual(t, TagInfo{Name: "incident-42", Count: 2}, infos[0])
+	assertEqual(t, TagInfo{Name: "web", Count: 1}, infos[1])
+
+	pastes, _, err := List(username, ListOptions{Tag: "web"})
+	assertNil(t, err)
+	assertEqual(t, 1, len(pastes))
+	assertEqual(t, nginx.Key, pastes[0].Key)
+	assertEqual(t, 2, len(pastes[0].Tags))
+
+	count, err := DeleteTag(username, "Incident-42")
+	assertNil(t, err)
+	assertEqual(t, int64(2), count)
+	tags, err = GetTags(nginx.Key, username)
+	assertNil(t, err)
+	assertEqual(t, 1, len(tags))
+	assertEqual(t, "web", tags[0])
+
+	// 彻底删除后标签一并删除
+	_, err = destroy([]string{nginx.Key})
+	assertNil(t, err)
+	infos, err = Tags(username)
+	assertNil(t, err)
+	assertEqual(t, 0, len(infos))
+}
@@ -1,9 +1,7 @@
 package paste
 
 import (
-	"github.com/PasteUs/PasteMeGoBackend/common/logging"
 	"github.com/PasteUs/PasteMeGoBackend/model/dao"
-	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"time"
@@ -11,46 +9,72 @@ import (
 
 const (
 	OneMonth = 31 * 24 * 60 * 60
-	MaxCount = 3
+	MaxCount = 1000 // 全局上限，各信任等级的限制由 handler 中的 authenticator 决定
 )
 
 var nilTime = time.Time{}
 
-func init() {
-	dao.CreateTable(&Temporary{})
-}
-
 // Temporary 临时
 type Temporary struct {
-	*AbstractPaste        // 公有字段
-	ExpireSecond   uint64 `json:"expire_second"` // 过期时间
-	ExpireCount    uint64 `json:"expire_count"` // 过期的次数
+	*AbstractPaste            // 公有字段
+	ExpireSecond   uint64     `json:"expire_second"`                                // 过期时间
+	ExpireCount    uint64     `json:"expire_count"`                                 // 过期的次数
+	ExpireAt       *time.Time `json:"expire_at,omitempty"`                          // 指定的过期时刻，使用 ExpireSecond 时为创建时间加上 ExpireSecond
+	IdleSecond     uint64     `json:"idle_second,omitempty"`                        // 超过若干秒没有被查看则过期，每次查看后重新计时
+	ExpiresAt      time.Time  `json:"expires_at" swaggerignore:"true" gorm:"index"` // 实际的过期时间，取以上两者中较早的一个，供后台清理使用
 }
 
 // Save 成员函数，保存
 func (paste *Temporary) Save() error {
-	paste.Key = generator(8, true, &paste)
-	paste.Password = hash(paste.Password)
-	err := dao.DB.Create(&paste).Error
-	if err == nil {
-		key := paste.Key
-		time.AfterFunc(time.Second*time.Duration(paste.ExpireSecond), func() {
-			if e := dao.DB.Delete(&Temporary{AbstractPaste: &AbstractPaste{Key: key}}).Error; e != nil {
-				logging.Error("delete paste failed", zap.String("key", paste.Key), zap.String("err", e.Error()))
-			}
-		})
+	now := time.Now()
+	if paste.ExpireAt == nil && paste.ExpireSecond > 0 {
+		expireAt := now.Add(time.Second * time.Duration(paste.ExpireSecond))
+		paste.ExpireAt = &expireAt
 	}
-	return err
+	paste.ExpiresAt = paste.deadline(now)
+	return paste.create("", true, func(tx *gorm.DB) error {
+		if temporary, err := isTemporaryKey(tx, paste.Key); err != nil {
+			return err
+		} else if !temporary {
+			return gorm.ErrDuplicatedKey // 转为永久的一贴保留了原来的 key，不能再次使用
+		}
+		return tx.Create(&paste).Error
+	})
+}
+
+// deadline 以 lastRead 为最后一次查看的时间计算实际的过期时间
+func (paste *Temporary) deadline(lastRead time.Time) time.Time {
+	var expiresAt time.Time
+	if paste.ExpireAt != nil {
+		expiresAt = *paste.ExpireAt
+	}
+	if paste.IdleSecond > 0 {
+		idle := lastRead.Add(time.Second * time.Duration(paste.IdleSecond))
+		if expiresAt.IsZero() || idle.Before(expiresAt) {
+			expiresAt = idle
+		}
+	}
+	if expiresAt.IsZero() {
+		expiresAt = paste.CreatedAt.Add(time.Second * time.Duration(paste.ExpireSecond)) // 旧记录只有 ExpireSecond
+	}
+	return expiresAt
 }
 
 // Delete 成员函数，删除
 func (paste *Temporary) Delete() error {
-	return dao.DB.Delete(&paste).Error
+	if err := paste.erase(dao.DB); err != nil {
+		return err
+	}
+	paste.removeContent()
+	return nil
 }
 
 func (paste *Temporary) Expired() bool {
-	duration := time.Second * time.Duration(paste.ExpireSecond)
-	if time.Now().After(paste.CreatedAt.Add(duration)) {
+	expiresAt := paste.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = paste.CreatedAt.Add(time.Second * time.Duration(paste.ExpireSecond))
+	}
+	if time.Now().After(expiresAt) {
 		return true
 	}
 	if paste.ExpireCount < 1 {
@@ -61,6 +85,7 @@ func (paste *Temporary) Expired() bool {
 
 // Get 成员函数，查看
 func (paste *Temporary) Get(password string) error {
+	deleted := false
 	if err := dao.DB.Transaction(func(tx *gorm.DB) error {
 		if e := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&paste).Error; e != nil {
 			return e
@@ -68,24 +93,46 @@ func (paste *Temporary) Get(password string) error {
 
 		if paste.Expired() {
 			paste.CreatedAt = nilTime // 通过此字段标记为非法，transaction 生效后再 return error
-			return tx.Delete(&paste).Error
+			return paste.erase(tx)
 		}
 
-		if e := paste.checkPassword(password); e != nil {
+		upgraded, e := paste.open(password) // 密码错误时回滚，不会消耗查看次数
+		if e != nil {
 			return e
 		}
 
 		paste.ExpireCount -= 1
 
 		if paste.Expired() {
-			return tx.Delete(&paste).Error
-		} else {
-			return tx.Save(&paste).Error
+			deleted = true
+			return paste.erase(tx)
+		}
+		updates := map[string]interface{}{"expire_count": paste.ExpireCount}
+		if paste.IdleSecond > 0 {
+			paste.ExpiresAt = paste.deadline(time.Now()) // 闲置过期从本次查看重新计时
+			updates["expires_at"] = paste.ExpiresAt
 		}
+		if upgraded {
+			updates["password"] = paste.Password
+		}
+		return tx.Model(&paste).Updates(updates).Error
 	}); err != nil {
 		return err
 	} else if paste.CreatedAt.IsZero() {
+		paste.removeContent()
 		return gorm.ErrRecordNotFound
 	}
-	return nil
+
+	if deleted {
+		paste.removeContent() // 最后一次查看，内容已经读出，可以删除
+	}
+	return paste.decode()
+}
+
+// erase 在事务中删除记录以及与之关联的数据，外部存储中的内容需要在事务生效后删除
+func (paste *Temporary) erase(tx *gorm.DB) error {
+	if err := tx.Delete(&paste).Error; err != nil {
+		return err
+	}
+	return detach(tx, paste.Key)
 }
@@ -2,7 +2,10 @@ package paste
 
 import (
 	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"github.com/PasteUs/PasteMeGoBackend/model/migration"
 	"gorm.io/gorm"
+	"os"
 	"testing"
 	"time"
 )
@@ -81,6 +84,65 @@ func TestTemporaryConcurrentGet(t *testing.T) {
 	assertEqual(t, expireCount, getCnt)
 }
 
+func TestSweep(t *testing.T) {
+	paste := Temporary{AbstractPaste: &AbstractPaste{}}
+	paste.ExpireCount = 1
+	paste.ExpireSecond = 10086
+
+	assertNil(t, paste.Save())
+	assertEqual(t, true, exist(paste.Key, &paste))
+	assertNil(t, dao.DB.Model(&paste).Update("expires_at", time.Now().Add(-time.Second)).Error)
+
+	_, err := sweep(500)
+	assertNil(t, err)
+	assertEqual(t, false, exist(paste.Key, &paste))
+}
+
+func TestTemporaryExpireAt(t *testing.T) {
+	expireAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	paste := Temporary{AbstractPaste: &AbstractPaste{}, ExpireAt: &expireAt, ExpireCount: 2}
+	assertNil(t, paste.Save())
+	assertEqual(t, true, paste.ExpiresAt.Equal(expireAt))
+
+	relative := Temporary{AbstractPaste: &AbstractPaste{}, ExpireSecond: 60, ExpireCount: 1}
+	assertNil(t, relative.Save())
+	assertEqual(t, true, relative.ExpireAt != nil && relative.ExpireAt.Equal(relative.ExpiresAt))
+
+	assertNil(t, dao.DB.Model(&paste).Update("expires_at", time.Now().Add(-time.Second)).Error)
+	assertEqual(t, gorm.ErrRecordNotFound, (&Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Get(""))
+}
+
+func TestTemporaryIdle(t *testing.T) {
+	expireAt := time.Now().Add(time.Hour)
+	paste := Temporary{AbstractPaste: &AbstractPaste{}, IdleSecond: 60, ExpireCount: 10}
+	capped := Temporary{AbstractPaste: &AbstractPaste{}, IdleSecond: 2 * 60 * 60, ExpireAt: &expireAt, ExpireCount: 10}
+	assertNil(t, paste.Save())
+	assertNil(t, capped.Save())
+	assertEqual(t, true, paste.ExpiresAt.Sub(time.Now()) <= time.Minute)
+	assertEqual(t, true, capped.ExpiresAt.Equal(expireAt)) // 不会晚于指定的过期时刻
+
+	// 模拟 50 秒没有被查看，查看后重新计时
+	assertNil(t, dao.DB.Model(&paste).Update("expires_at", time.Now().Add(10*time.Second)).Error)
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, true, got.ExpiresAt.After(time.Now().Add(50*time.Second)))
+
+	stored := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, dao.DB.Take(&stored).Error)
+	assertEqual(t, true, stored.ExpiresAt.After(time.Now().Add(50*time.Second)))
+	assertEqual(t, uint64(9), stored.ExpireCount)
+
+	// 闲置超时后不能再读取
+	assertNil(t, dao.DB.Model(&paste).Update("expires_at", time.Now().Add(-time.Second)).Error)
+	assertEqual(t, gorm.ErrRecordNotFound, (&Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Get(""))
+}
+
 func TestMain(m *testing.M) {
-	m.Run()
+	if err := migration.Up(); err != nil {
+		panic(err)
+	}
+	StartSweeper(time.Millisecond*100, 500)
+	code := m.Run()
+	StopSweeper()
+	os.Exit(code)
 }
@@ -0,0 +1,69 @@
+package paste
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const deleteTokenLength = 32
+
+// newDeleteToken 生成随机的删除凭证，返回明文与保存到数据库中的哈希
+func newDeleteToken() (token string, hashed string, err error) {
+	buffer := make([]byte, deleteTokenLength)
+	if _, err = rand.Read(buffer); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buffer)
+	return token, hashDeleteToken(token), nil
+}
+
+// hashDeleteToken 删除凭证本身是高熵的随机数，使用 sha256 即可，不需要 argon2id
+func hashDeleteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkDeleteToken 校验删除凭证，没有保存哈希的旧记录总是校验失败
+func (paste *AbstractPaste) checkDeleteToken(token string) error {
+	if paste.DeleteTokenHash == "" || token == "" ||
+		subtle.ConstantTimeCompare([]byte(paste.DeleteTokenHash), []byte(hashDeleteToken(token))) != 1 {
+		return common.ErrWrongDeleteToken
+	}
+	return nil
+}
+
+// Revoke 成员函数，使用删除凭证立即删除，不需要登陆
+func (paste *Temporary) Revoke(token string) error {
+	if err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&paste).Error; err != nil {
+			return err
+		}
+		if err := paste.checkDeleteToken(token); err != nil {
+			return err
+		}
+		return paste.erase(tx)
+	}); err != nil {
+		return err
+	}
+	paste.removeContent()
+	return nil
+}
+
+// Revoke 成员函数，使用删除凭证彻底删除，包括回收站中的记录和全部修订记录，不需要登陆
+func (paste *Permanent) Revoke(token string) error {
+	if err := dao.DB.Unscoped().Select("key", "delete_token_hash").Take(&paste).Error; err != nil {
+		return err
+	}
+	if err := paste.checkDeleteToken(token); err != nil {
+		return err
+	}
+	_, err := destroy([]string{paste.Key})
+	return err
+}
@@ -0,0 +1,50 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestTemporaryRevoke(t *testing.T) {
+	fs := useFilesystemStore(t)
+	paste := Temporary{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "leaked secret"}, ExpireSecond: 60, ExpireCount: 10}
+	assertNil(t, paste.Save())
+	token := paste.GetDeleteToken()
+	assertEqual(t, true, token != "")
+	assertEqual(t, hashDeleteToken(token), paste.DeleteTokenHash)
+
+	for _, wrong := range []string{"", "wrong", paste.DeleteTokenHash} {
+		if err := (&Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Revoke(wrong); !errors.Is(err, common.ErrWrongDeleteToken) {
+			t.Fatalf("expected wrong delete token, got %v", err)
+		}
+	}
+	assertNil(t, (&Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Revoke(token))
+
+	got := Temporary{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	if err := got.Get(""); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+	if _, err := fs.Get(paste.Key); err == nil {
+		t.Fatal("content not removed")
+	}
+}
+
+func TestPermanentRevoke(t *testing.T) {
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "leaked secret"}}
+	assertNil(t, paste.Save())
+	token := paste.GetDeleteToken()
+
+	other := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "another"}}
+	assertNil(t, other.Save())
+	defer func() {
+		_, _ = destroy([]string{other.Key})
+	}()
+	if err := (&Permanent{AbstractPaste: &AbstractPaste{Key: other.Key}}).Revoke(token); !errors.Is(err, common.ErrWrongDeleteToken) {
+		t.Fatalf("expected wrong delete token, got %v", err)
+	}
+
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Revoke(token))
+	assertEqual(t, int64(0), countUnscoped(t, &Permanent{}, paste.Key)) // 彻底删除，不进入回收站
+}
@@ -0,0 +1,151 @@
+package paste
+
+import (
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// TrashInfo 回收站中的永久 paste，不包含内容
+type TrashInfo struct {
+	Key       string     `json:"key" example:"a1b2c3d4"`
+	Lang      string     `json:"lang" example:"plain"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"` // 超过该时间后不能恢复，不限制保留时间时为空
+}
+
+// retention 删除的永久 paste 在回收站中的保留时间，为 0 时不限制
+func retention() time.Duration {
+	return time.Duration(config.Config.Trash.RetentionDays) * 24 * time.Hour
+}
+
+// purgeAt 在 deletedAt 删除的永久 paste 超过保留时间的时刻，不限制保留时间时返回 nil
+func purgeAt(deletedAt time.Time) *time.Time {
+	if retention() == 0 {
+		return nil
+	}
+	at := deletedAt.Add(retention())
+	return &at
+}
+
+// Remove 成员函数，将永久 paste 移入回收站，只有创建者和管理员可以删除
+func (paste *Permanent) Remove(username string, admin bool) error {
+	defer readCache.invalidate(paste.Key)
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&paste).Error; err != nil {
+			return err
+		}
+		if !admin && (paste.Username == "" || paste.Username != username) {
+			return common.ErrNotOwner
+		}
+		return tx.Delete(&paste).Error
+	})
+}
+
+// Restore 成员函数，恢复回收站中未超过保留时间的永久 paste，只有创建者和管理员可以恢复
+func (paste *Permanent) Restore(username string, admin bool) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Take(&paste).Error; err != nil {
+			return err
+		}
+		if !paste.DeletedAt.Valid {
+			return gorm.ErrRecordNotFound // 不在回收站中
+		}
+		if at := purgeAt(paste.DeletedAt.Time); at != nil && at.Before(time.Now()) {
+			return gorm.ErrRecordNotFound // 已经超过保留时间等待清理
+		}
+		if !admin && (paste.Username == "" || paste.Username != username) {
+			return common.ErrNotOwner
+		}
+		paste.DeletedAt = gorm.DeletedAt{}
+		updates := map[string]interface{}{"deleted_at": nil}
+		if paste.Scheduled() {
+			paste.DeleteAt = nil // 否则恢复后会再次被移入回收站
+			updates["delete_at"] = nil
+		}
+		return tx.Unscoped().Model(&paste).Updates(updates).Error
+	})
+}
+
+// Trash 列出用户回收站中可以恢复的永久 paste，按删除时间从新到旧排列
+func Trash(username string) ([]TrashInfo, error) {
+	var pastes []Permanent
+	query := dao.DB.Unscoped().Select("key", "lang", "created_at", "deleted_at").
+		Where(map[string]interface{}{"username": username}).Where("deleted_at IS NOT NULL")
+	if retention() > 0 {
+		query = query.Where("deleted_at > ?", time.Now().Add(-retention()))
+	}
+	if err := query.Order("deleted_at desc").Find(&pastes).Error; err != nil {
+		return nil, err
+	}
+
+	trash := make([]TrashInfo, 0, len(pastes))
+	for _, paste := range pastes {
+		trash = append(trash, TrashInfo{
+			Key:       paste.Key,
+			Lang:      paste.Lang,
+			CreatedAt: paste.CreatedAt,
+			DeletedAt: paste.DeletedAt.Time,
+			PurgeAt:   purgeAt(paste.DeletedAt.Time),
+		})
+	}
+	return trash, nil
+}
+
+// purge 彻底删除至多 batchSize 条在 before 之前删除的永久 paste 及其修订记录，返回删除的条数
+func purge(before time.Time, batchSize int) (int64, error) {
+	var keys []string
+	if err := dao.DB.Unscoped().Model(&Permanent{}).Where("deleted_at <= ?", before).
+		Limit(batchSize).Pluck("key", &keys).Error; err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	return destroy(keys)
+}
+
+// destroy 彻底删除永久 paste 及其修订记录，包括外部存储中的内容，返回删除的条数
+func destroy(keys []string) (int64, error) {
+	var (
+		deleted   []Permanent
+		revisions []PasteRevision
+		count     int64
+	)
+	if err := dao.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Select("key", "content_store", "revision").Where(map[string]interface{}{"key": keys}).
+			Find(&deleted).Error; err != nil {
+			return err
+		}
+		if err := tx.Select("key", "content_store", "revision").Where(map[string]interface{}{"key": keys}).
+			Find(&revisions).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(map[string]interface{}{"key": keys}).Delete(&PasteRevision{AbstractPaste: &AbstractPaste{}}).Error; err != nil {
+			return err
+		}
+		if err := detach(tx, keys...); err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where(map[string]interface{}{"key": keys}).Delete(&Permanent{AbstractPaste: &AbstractPaste{}})
+		count = result.RowsAffected
+		return result.Error
+	}); err != nil {
+		return 0, err
+	}
+	readCache.invalidate(keys...)
+
+	for _, paste := range deleted {
+		paste.storeKey = revisionKey(paste.Key, paste.Revision)
+		paste.removeContent()
+	}
+	for _, revision := range revisions {
+		revision.storeKey = revisionKey(revision.Key, revision.Revision)
+		revision.removeContent()
+	}
+	return count, nil
+}
@@ -0,0 +1,152 @@
+package paste
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/handler/common"
+	"github.com/PasteUs/PasteMeGoBackend/model/dao"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func countUnscoped(t *testing.T, model interface{}, key string) int64 {
+	count := int64(0)
+	assertNil(t, dao.DB.Unscoped().Model(model).Where(map[string]interface{}{"key": key}).Count(&count).Error)
+	return count
+}
+
+func TestPermanentRemoveRestore(t *testing.T) {
+	paste := editedPermanent(t, "trash-notes", "alice", "content")
+
+	if err := (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Remove("bob", false); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Remove("alice", false))
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	if err := got.Get(""); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+
+	trash, err := Trash("alice")
+	assertNil(t, err)
+	assertEqual(t, 1, len(trash))
+	assertEqual(t, paste.Key, trash[0].Key)
+	assertEqual(t, true, trash[0].PurgeAt.After(time.Now()))
+
+	if err := (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Restore("bob", false); !errors.Is(err, common.ErrNotOwner) {
+		t.Fatalf("expected not owner, got %v", err)
+	}
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Restore("bob", true)) // 管理员可以恢复任意 paste
+
+	got = Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "content", got.Content)
+
+	trash, err = Trash("alice")
+	assertNil(t, err)
+	assertEqual(t, 0, len(trash))
+
+	// 不在回收站中的 paste 不能恢复
+	if err := (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Restore("alice", false); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	fs := useFilesystemStore(t)
+	paste := editedPermanent(t, "purged-notes", "alice", "version one")
+	edit := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key, Lang: "plain", Content: "version two"}}
+	assertNil(t, edit.Edit("alice", 1))
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Remove("alice", false))
+
+	// 保留时间内不会被清理
+	_, err := purge(time.Now().Add(-time.Hour), 500)
+	assertNil(t, err)
+	assertEqual(t, int64(1), countUnscoped(t, &Permanent{}, paste.Key))
+
+	// 超过保留时间后不能恢复
+	expired := time.Now().Add(-retention() - time.Hour)
+	assertNil(t, dao.DB.Unscoped().Model(&Permanent{}).Where(map[string]interface{}{"key": paste.Key}).Update("deleted_at", expired).Error)
+	err = (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Restore("alice", false)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+	trash, err := Trash("alice")
+	assertNil(t, err)
+	for _, info := range trash {
+		if info.Key == paste.Key {
+			t.Fatalf("expired paste %s still listed in trash", paste.Key)
+		}
+	}
+
+	_, err = purge(time.Now(), 500)
+	assertNil(t, err)
+	assertEqual(t, int64(0), countUnscoped(t, &Permanent{}, paste.Key))
+	assertEqual(t, int64(0), countUnscoped(t, &PasteRevision{}, paste.Key))
+	for _, key := range []string{revisionKey(paste.Key, 1), revisionKey(paste.Key, 2)} {
+		if _, err := fs.Get(key); err == nil {
+			t.Fatalf("content %s not removed", key)
+		}
+	}
+}
+
+func TestUnlimitedRetention(t *testing.T) {
+	retentionDays := config.Config.Trash.RetentionDays
+	config.Config.Trash.RetentionDays = 0
+	defer func() { config.Config.Trash.RetentionDays = retentionDays }()
+
+	paste := editedPermanent(t, "kept-notes", "keeper", "content")
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Remove("keeper", false))
+	longAgo := time.Now().AddDate(-1, 0, 0)
+	assertNil(t, dao.DB.Unscoped().Model(&Permanent{}).Where(map[string]interface{}{"key": paste.Key}).Update("deleted_at", longAgo).Error)
+
+	// 保留天数为 0 时回收站中的 paste 永远不会被清理，也一直可以恢复
+	(&sweeper{batchSize: 500, stop: make(chan struct{})}).sweepAll()
+	trash, err := Trash("keeper")
+	assertNil(t, err)
+	assertEqual(t, 1, len(trash))
+	assertEqual(t, true, trash[0].PurgeAt == nil)
+
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Restore("keeper", false))
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, "content", got.Content)
+}
+
+func TestRetire(t *testing.T) {
+	deleteAt := time.Now().Add(time.Hour)
+	paste := Permanent{AbstractPaste: &AbstractPaste{Lang: "plain", Content: "sprint notes", Username: "retiree"}, DeleteAt: &deleteAt}
+	assertNil(t, paste.Save())
+
+	got := Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	meta, err := ReadMeta(paste.Key)
+	assertNil(t, err)
+	assertEqual(t, true, meta.ExpiresAt.Equal(deleteAt))
+
+	assertNil(t, dao.DB.Model(&paste).Update("delete_at", time.Now().Add(-time.Second)).Error)
+	readCache.invalidate(paste.Key)
+	if err = (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Get(""); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+	if _, err = ReadMeta(paste.Key); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+
+	_, err = retire(500)
+	assertNil(t, err)
+	trash, err := Trash("retiree")
+	assertNil(t, err)
+	assertEqual(t, 1, len(trash))
+	assertEqual(t, paste.Key, trash[0].Key)
+
+	// 恢复后清除已经过去的删除时间，不会再次被移入回收站
+	assertNil(t, (&Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}).Restore("retiree", false))
+	_, err = retire(500)
+	assertNil(t, err)
+	got = Permanent{AbstractPaste: &AbstractPaste{Key: paste.Key}}
+	assertNil(t, got.Get(""))
+	assertEqual(t, true, got.DeleteAt == nil)
+}
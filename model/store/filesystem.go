@@ -0,0 +1,82 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Filesystem 将内容按 key 存放在本地目录中
+type Filesystem struct {
+	root string
+}
+
+func NewFilesystem(root string) (*Filesystem, error) {
+	if err := os.MkdirAll(root, 0750); err != nil {
+		return nil, err
+	}
+	return &Filesystem{root: root}, nil
+}
+
+func (fs *Filesystem) Name() string {
+	return "filesystem"
+}
+
+// path 按 key 的前两个字符分目录，避免单个目录下文件过多
+func (fs *Filesystem) path(key string) (string, error) {
+	if len(key) < 2 || strings.ContainsAny(key, `/\.`) {
+		return "", fmt.Errorf("invalid content key %q", key)
+	}
+	return filepath.Join(fs.root, key[:2], key), nil
+}
+
+func (fs *Filesystem) Put(key string, content []byte) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，保证读到的内容总是完整的
+	file, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(content); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (fs *Filesystem) Get(key string) ([]byte, error) {
+	path, err := fs.path(key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return content, err
+}
+
+func (fs *Filesystem) Delete(key string) error {
+	path, err := fs.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3 通过 path-style 请求访问兼容 S3 协议的对象存储，例如 MinIO
+type S3 struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+func NewS3(c config.S3) *S3 {
+	region := c.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		endpoint:  strings.TrimSuffix(c.Endpoint, "/"),
+		region:    region,
+		bucket:    c.Bucket,
+		accessKey: c.AccessKey,
+		secretKey: c.SecretKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		now:       time.Now,
+	}
+}
+
+func (s *S3) Name() string {
+	return "s3"
+}
+
+func (s *S3) Put(key string, content []byte) error {
+	response, err := s.do(http.MethodPut, key, content)
+	if err != nil {
+		return err
+	}
+	defer closeBody(response.Body)
+	if response.StatusCode != http.StatusOK {
+		return s.error(http.MethodPut, key, response)
+	}
+	return nil
+}
+
+func (s *S3) Get(key string) ([]byte, error) {
+	response, err := s.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(response.Body)
+	switch response.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(response.Body)
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, s.error(http.MethodGet, key, response)
+	}
+}
+
+func (s *S3) Delete(key string) error {
+	response, err := s.do(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer closeBody(response.Body)
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK &&
+		response.StatusCode != http.StatusNotFound {
+		return s.error(http.MethodDelete, key, response)
+	}
+	return nil
+}
+
+func (s *S3) error(method string, key string, response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return fmt.Errorf("s3 %s %s failed, status = %d, body = %s", method, key, response.StatusCode, body)
+}
+
+func closeBody(body io.ReadCloser) {
+	_ = body.Close()
+}
+
+func (s *S3) do(method string, key string, content []byte) (*http.Response, error) {
+	path := "/" + s.bucket + "/" + url.PathEscape(key)
+	request, err := http.NewRequest(method, s.endpoint+path, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(request, path, content)
+	return s.client.Do(request)
+}
+
+// sign 按 AWS Signature Version 4 为请求签名
+func (s *S3) sign(request *http.Request, path string, content []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(content)
+
+	request.Header.Set("Host", request.URL.Host)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		"",
+		"host:" + request.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
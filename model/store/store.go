@@ -0,0 +1,61 @@
+package store
+
+import (
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"github.com/PasteUs/PasteMeGoBackend/common/flag"
+	"github.com/PasteUs/PasteMeGoBackend/common/logging"
+	"go.uber.org/zap"
+	"path/filepath"
+)
+
+// ErrNotFound 内容不存在
+var ErrNotFound = errors.New("content not found")
+
+// ContentStore 存放较大的 paste 内容，数据库中只保留元数据
+type ContentStore interface {
+	Name() string
+	Put(key string, content []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+var (
+	Default   ContentStore // 为 nil 时所有内容都存放在数据库中
+	Threshold int          // 超过该字节数的内容才会放入 Default
+)
+
+func init() {
+	storage := config.Config.Storage
+	Threshold = storage.Threshold
+
+	switch storage.Type {
+	case "filesystem":
+		root := storage.Root
+		if root == "" {
+			root = filepath.Join(flag.DataDir, "content")
+		}
+		fs, err := NewFilesystem(root)
+		if err != nil {
+			logging.Panic("init filesystem content store failed", zap.String("root", root), zap.Error(err))
+			return
+		}
+		Default = fs
+	case "s3":
+		Default = NewS3(storage.S3)
+	case "", "database":
+		return
+	default:
+		logging.Panic("unknown content store type", zap.String("type", storage.Type))
+		return
+	}
+	logging.Info("content store enabled", zap.String("type", Default.Name()), zap.Int("threshold", Threshold))
+}
+
+// Get 根据名字取对应的 ContentStore，名字与 Default 不符时返回 nil
+func Get(name string) ContentStore {
+	if Default != nil && Default.Name() == name {
+		return Default
+	}
+	return nil
+}
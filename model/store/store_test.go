@@ -0,0 +1,99 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/PasteUs/PasteMeGoBackend/common/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func assertNil(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func testContentStore(t *testing.T, store ContentStore) {
+	content := []byte("Hello World!")
+
+	assertNil(t, store.Put("a1b2c3d4", content))
+	got, err := store.Get("a1b2c3d4")
+	assertNil(t, err)
+	if !bytes.Equal(content, got) {
+		t.Fatalf("expect %s, got %s", content, got)
+	}
+
+	assertNil(t, store.Delete("a1b2c3d4"))
+	if _, err = store.Get("a1b2c3d4"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expect %v, got %v", ErrNotFound, err)
+	}
+	assertNil(t, store.Delete("a1b2c3d4"))
+}
+
+func TestFilesystem(t *testing.T) {
+	fs, err := NewFilesystem(t.TempDir())
+	assertNil(t, err)
+	testContentStore(t, fs)
+
+	if err = fs.Put("../escape", []byte{}); err == nil {
+		t.Fatal("expect invalid key error")
+	}
+}
+
+// fakeS3 模拟 MinIO 的对象读写接口
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		if object, ok := f.objects[r.URL.Path]; ok {
+			_, _ = w.Write(object)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	testContentStore(t, NewS3(config.S3{
+		Endpoint:  server.URL,
+		Bucket:    "pasteme",
+		AccessKey: "minio",
+		SecretKey: "minio123",
+	}))
+	if len(fake.objects) != 0 {
+		t.Fatalf("expect empty bucket, got %d objects", len(fake.objects))
+	}
+}
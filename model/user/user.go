@@ -1,13 +1,28 @@
 package user
 
-import "github.com/PasteUs/PasteMeGoBackend/model/dao"
-
-func init() {
-	dao.CreateTable(&User{})
-}
+import "github.com/PasteUs/PasteMeGoBackend/common/password"
 
 type User struct {
 	Username string `json:"username" gorm:"type:varchar(32);primaryKey"`
-	Password string `json:"password" gorm:"type:varchar(32)"`
+	Password string `json:"password" gorm:"type:varchar(128)"` // 密码的 argon2id 哈希
 	Email    string `json:"email" gorm:"type:varchar(128)"`
 }
+
+// SetPassword 保存密码的哈希
+func (user *User) SetPassword(text string) error {
+	hashed, err := password.Hash(text)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	return nil
+}
+
+// CheckPassword 校验密码，旧的 MD5 哈希校验通过后会替换为新的哈希，upgraded 表示 Password 需要写回数据库
+func (user *User) CheckPassword(text string) (ok bool, upgraded bool) {
+	ok, needRehash := password.Verify(user.Password, text)
+	if !ok || !needRehash {
+		return ok, false
+	}
+	return true, user.SetPassword(text) == nil
+}
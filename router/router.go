@@ -1,6 +1,8 @@
 package router
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/PasteUs/PasteMeGoBackend/common/flag"
 	"github.com/PasteUs/PasteMeGoBackend/common/logging"
@@ -9,6 +11,11 @@ import (
 	"github.com/PasteUs/PasteMeGoBackend/handler/token"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 var router *gin.Engine
@@ -43,13 +50,62 @@ func init() {
 				u.POST("")
 				u.DELETE("")
 				u.PUT("")
+				u.GET("/trash", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Trash) // 列出回收站中的 Paste
+				u.GET("/pastes", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.List) // 列出当前用户的 Paste
+				u.GET("/pastes/search", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Search) // 搜索当前用户的 Paste
+				u.GET("/pastes/:key/tags", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.GetTags) // 读取 Paste 的标签
+				u.PUT("/pastes/:key/tags", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.SetTags) // 设置 Paste 的标签
+				u.GET("/tags", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.UserTags) // 列出当前用户的标签
+				u.DELETE("/tags/:tag", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.DeleteTag) // 从全部 Paste 上移除标签
+				u.GET("/collections", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Collections) // 列出当前用户的合集
+				u.POST("/collections", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.CreateCollection) // 创建合集
+				u.PUT("/collections/:id", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.UpdateCollection) // 修改合集
+				u.DELETE("/collections/:id", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.DeleteCollection) // 删除合集
+			}
+
+			v3.GET("/collection/:id", paste.GetCollection) // 读取合集
+
+			a := v3.Group("/admin")
+			{
+				a.GET("/cache", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.CacheStats) // 读缓存的命中统计
 			}
 
 			p := v3.Group("/paste")
 			{
 				p.POST("/", token.AuthMiddleware.MiddlewareFunc(true),
 					paste.Create) // 创建一个 Paste
-				p.GET("/:key", paste.Get) // 读取 Paste
+				p.GET("/:key", paste.Get)   // 读取 Paste
+				p.HEAD("/:key", paste.Meta) // 只返回元信息的响应头，不消耗查看次数
+				p.PUT("/:key", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Edit) // 修改 Paste
+				p.GET("/:key/revisions", paste.Revisions) // 列出 Paste 的全部版本
+				p.GET("/:key/download", paste.Download)   // 打包下载 Paste 中的全部文件
+				p.GET("/:key/forks", paste.Forks)         // 列出 Paste 的 fork
+				p.GET("/:key/:filename", paste.GetFile)   // 读取多文件 Paste 中的一个文件
+				p.GET("/:key/stats", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Stats) // Paste 的查看统计
+				p.GET("/:key/meta", paste.Meta)      // 读取 Paste 的元信息，不消耗查看次数
+				p.POST("/:key/reveal", paste.Reveal) // 确认后读取 Paste
+				p.DELETE("/:key", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Delete) // 删除 Paste，移入回收站
+				p.POST("/:key/restore", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Restore) // 从回收站恢复 Paste
+				p.POST("/:key/fork", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Fork) // 以 Paste 的内容创建新的 Paste
+				p.PATCH("/:key/expiry", token.AuthMiddleware.MiddlewareFunc(true),
+					paste.Extend) // 延长 Paste 的过期时间，或转为永久的 Paste
 			}
 		}
 	}
@@ -57,8 +113,27 @@ func init() {
 	router.NoRoute(common.NotFoundHandler)
 }
 
+// Run 启动服务，收到 SIGINT 或 SIGTERM 后等待处理中的请求结束再返回
 func Run(address string, port uint16) {
-	if err := router.Run(fmt.Sprintf("%s:%d", address, port)); err != nil {
-		logging.Panic("Run server failed", zap.Error(err))
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", address, port),
+		Handler: router,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Panic("Run server failed", zap.Error(err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logging.Info("shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logging.Error("shutdown server failed", zap.Error(err))
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/PasteUs/PasteMeGoBackend/model/migration"
 	"io"
 	"io/ioutil"
 	"net/http/httptest"
@@ -150,5 +151,8 @@ func Test(t *testing.T) {
 }
 
 func TestMain(m *testing.M) {
+	if err := migration.Up(); err != nil {
+		panic(err)
+	}
 	m.Run()
 }